@@ -1,16 +1,22 @@
 package actions
 
 import (
-	"math/rand"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/solarlune/routine"
 )
 
-// Wait is an action that waits a customizeable amount of time before continuing.
+// Wait is an action that waits a customizeable amount of time before continuing. The time waited is measured using the
+// Block's time (see Block.RunningTime()), so the Wait doesn't progress while the Block is paused or otherwise isn't
+// being updated, and picks up where it left off once the Block is run again.
+// The Wait's start is stored per Block (see Block.SetActionState()), so it can be shared between Blocks and is rolled
+// back with its Block's state (see Routine.RestoreStateFrom()).
 type Wait struct {
 	Duration   time.Duration
 	targetTime time.Time
+	routine.Annotated
 }
 
 // NewWait creates a new Wait Action.
@@ -18,66 +24,237 @@ func NewWait(duration time.Duration) *Wait {
 	wait := &Wait{
 		Duration: duration,
 	}
-	return wait
+	return Captured(wait)
 }
 
 func (w *Wait) Init(block *routine.Block) {
-	w.targetTime = time.Now().Add(w.Duration)
+	w.targetTime = wakeTime(block.Routine(), w.Duration)
+	block.SetActionState(w, actionStart{time: block.RunningTime(), frame: block.RunningFrames()})
 }
 
 func (w *Wait) Poll(block *routine.Block) routine.Flow {
-	if time.Now().After(w.targetTime) {
+	r := block.Routine()
+	start, _ := block.ActionState(w).(actionStart)
+	if r.TickRate() > 0 {
+		if block.RunningFrames()-start.frame >= r.DurationToTicks(w.Duration) {
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	}
+	elapsed := block.RunningTime() - start.time
+	if elapsed >= w.Duration {
+		return routine.FlowNext
+	}
+	w.targetTime = wakeTime(r, w.Duration-elapsed)
+	return routine.FlowIdle
+}
+
+// WakeTime returns the time at which the Wait will finish (assuming its Block keeps being updated and the Routine's time
+// scale doesn't change), allowing the Routine to know when it next needs to be updated.
+func (w *Wait) WakeTime() time.Time {
+	return w.targetTime
+}
+
+// wakeTime returns the time on the Routine's Clock at which the given amount of the Routine's time will have passed,
+// taking its time scale (see Routine.SetTimeScale()) into account.
+func wakeTime(r *routine.Routine, remaining time.Duration) time.Time {
+	if scale := r.TimeScale(); scale > 0 && scale != 1 {
+		remaining = time.Duration(float64(remaining) / scale)
+	}
+	return r.Clock().Now().Add(remaining)
+}
+
+func (w *Wait) Name() string { return "Wait " + w.Duration.String() }
+
+// WaitThen is an action that waits a customizeable amount of time, and then runs a function before continuing.
+// It's equivalent to a Wait followed by a Function action.
+type WaitThen struct {
+	Wait
+	Then func()
+}
+
+// NewWaitThen creates a new WaitThen Action, which waits for the given duration and then calls the provided function.
+func NewWaitThen(duration time.Duration, then func()) *WaitThen {
+	return Captured(&WaitThen{
+		Wait: Wait{Duration: duration},
+		Then: then,
+	})
+}
+
+func (w *WaitThen) Poll(block *routine.Block) routine.Flow {
+	if w.Wait.Poll(block) == routine.FlowNext {
+		if w.Then != nil {
+			w.Then()
+		}
 		return routine.FlowNext
 	}
 	return routine.FlowIdle
 }
 
+func (w *WaitThen) Name() string { return "WaitThen " + w.Duration.String() }
+
+// NewWaitWithProgress creates a new Function action that waits for the given duration before proceeding, calling
+// onProgress every poll with the normalized progress of the wait (ranging from 0 to 1, inclusive). This can be used
+// to directly drive things like loading bars or charge-up indicators.
+// Like Wait, the time waited is measured using the Block's time (see Block.RunningTime()), so the wait doesn't progress
+// while the Block isn't being updated.
+func NewWaitWithProgress(duration time.Duration, onProgress func(t float64)) *Function {
+	return newTimedFunction(func(block *routine.Block, elapsed time.Duration, frames int) routine.Flow {
+
+		t := 1.0
+		if duration > 0 {
+			t = float64(elapsed) / float64(duration)
+		}
+
+		if t >= 1 {
+			onProgress(1)
+			return routine.FlowNext
+		}
+
+		onProgress(t)
+		return routine.FlowIdle
+
+	}).SetName("WaitWithProgress " + duration.String())
+}
+
 // NewWaitTicks creates a new action that waits a certain amount of time before proceeding.
 func NewWaitTicks(tickCount int) *Function {
-	return NewFunction(func(block *routine.Block) routine.Flow {
+	return newTimedFunction(func(block *routine.Block, elapsed time.Duration, frames int) routine.Flow {
 
-		if block.CurrentFrame() >= tickCount {
+		if frames >= tickCount {
 			return routine.FlowNext
 		}
 
 		return routine.FlowIdle
 
-	})
+	}).SetName(fmt.Sprintf("WaitTicks %d", tickCount))
 }
 
-// NewWaitTicks creates a new action that waits a random amount of time, ranging between minTime and maxTime, before proceeding.
+// NewWaitTicksRandom creates a new action that waits a random number of ticks, ranging between minTime and maxTime
+// (inclusive), before proceeding. The number of ticks is chosen each time the action starts, using the Routine's random
+// source (see Routine.Rand()), and is stored per Block, so the action can be shared between Blocks. If minTime is
+// greater than maxTime, an error is reported to the Routine's error handler and the bounds are swapped.
 func NewWaitTicksRandom(minTime, maxTime int) *Function {
 
-	tickCount := 0
+	choose := func(block *routine.Block) any {
+		low, high := minTime, maxTime
+		if low > high {
+			block.Routine().ReportError(fmt.Errorf("actions: WaitTicksRandom in block %v has a minimum (%d) greater than its maximum (%d)", block.ID, minTime, maxTime))
+			low, high = high, low
+		}
+		return low + block.Routine().Rand().Intn(high-low+1)
+	}
 
-	return NewFunction(func(block *routine.Block) routine.Flow {
+	f := newChoosingFunction(choose, func(block *routine.Block, tickCount any, elapsed time.Duration, frames int) routine.Flow {
+		if count, _ := tickCount.(int); frames >= count {
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	})
+
+	return f.SetName(fmt.Sprintf("WaitTicksRandom %d-%d", minTime, maxTime))
+
+}
 
-		if block.CurrentFrame() == 0 {
-			tickCount = minTime + int((float64(maxTime-minTime) * rand.Float64()))
+// NewWaitJitter creates a new Function action that waits for the base duration, randomly varied by up to the given
+// fraction of it in either direction (e.g. a jitterFraction of 0.25 waits between 75% and 125% of the base duration),
+// before proceeding. This can be used to make timing look organic (like the idle behavior of NPCs) with a single call.
+// The duration is chosen each time the action starts, using the Routine's random source (see Routine.Rand()), and is
+// stored per Block, so the action can be shared between Blocks. Like NewWaitWithProgress(), the time waited is
+// measured using the Block's time (see Block.RunningTime()).
+func NewWaitJitter(base time.Duration, jitterFraction float64) *Function {
+
+	choose := func(block *routine.Block) any {
+		jitter := (block.Routine().Rand().Float64()*2 - 1) * jitterFraction
+		duration := time.Duration(float64(base) * (1 + jitter))
+		if duration < 0 {
+			duration = 0
 		}
+		return duration
+	}
 
-		if block.CurrentFrame() >= tickCount {
+	f := newChoosingFunction(choose, func(block *routine.Block, duration any, elapsed time.Duration, frames int) routine.Flow {
+		if length, _ := duration.(time.Duration); elapsed >= length {
 			return routine.FlowNext
 		}
+		return routine.FlowIdle
+	})
+
+	return f.SetName(fmt.Sprintf("WaitJitter %s±%g%%", base, jitterFraction*100))
 
+}
+
+// NewWaitForMessage creates a new Function action that idles until a message for which match returns true is sent
+// to the Block's Routine (see Routine.Send()). The message is then removed from the Routine's inbox and set as the
+// Block's result (see Block.LastResult()), so that following Actions can read it. If match is nil, any message is accepted.
+func NewWaitForMessage(match func(message any) bool) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		if message, ok := block.Routine().Receive(match); ok {
+			block.SetResult(message)
+			return routine.FlowNext
+		}
 		return routine.FlowIdle
+	}).SetName("WaitForMessage")
+}
 
-	})
+// NewWaitForRoutine creates a new Function action that idles until none of the Blocks with the given IDs in another
+// Routine are running (see Routine.Running()), allowing Routines to be sequenced from scripts (e.g. a level's Routine
+// waiting for an intro's Routine to finish). If no IDs are given, it idles until no Block in that Routine is running.
+// As the other Routine's Blocks are checked when the action is polled, both Routines should be updated from the same
+// goroutine.
+func NewWaitForRoutine(other *routine.Routine, blockIDs ...any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		if other.Running(blockIDs...) {
+			return routine.FlowIdle
+		}
+		return routine.FlowNext
+	}).SetName("WaitForRoutine")
+}
+
+// NewWaitUntil creates a new Function action that idles until the given condition function returns true.
+func NewWaitUntil(condition func() bool) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		if condition() {
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	}).SetName("WaitUntil")
 }
 
 // Function is a Action that runs a customizeable function.
 type Function struct {
 	InitFunc func(block *routine.Block)              // The function to run when the ActionFunc object is initialized (before polling)
 	PollFunc func(block *routine.Block) routine.Flow // The function to run when polled
+	EndFunc  func(block *routine.Block)              // The function to run when the Block moves off of the Function (see routine.ActionEndable)
+	name     string
+	routine.Annotated
 }
 
 // NewFunction creates and returns a Function action object with the polling function set to the
 // provided function. The routine.Flow returned from the customizeable function influences
 // the Routine does after running the function.
 func NewFunction(function func(block *routine.Block) routine.Flow) *Function {
-	return &Function{
+	return Captured(&Function{
 		PollFunc: function,
-	}
+	})
+}
+
+// NewFunctionWithArgs creates and returns a Function action object that calls the provided function with the
+// arguments the Block was run with (see Block.RunWith()), typed as T. If the Block wasn't run with arguments, the zero
+// value of T is passed instead; if it was run with arguments of another type, an error is reported to the Routine's
+// error handler and the zero value of T is passed as well.
+func NewFunctionWithArgs[T any](function func(block *routine.Block, args T) routine.Flow) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		var args T
+		if block.Args() != nil {
+			typed, ok := block.Args().(T)
+			if !ok {
+				block.Routine().ReportError(fmt.Errorf("actions: block %v was run with arguments of type %T, expected %T", block.ID, block.Args(), args))
+			}
+			args = typed
+		}
+		return function(block, args)
+	}).SetName("FunctionWithArgs")
 }
 
 func (f *Function) Init(block *routine.Block) {
@@ -88,44 +265,138 @@ func (f *Function) Init(block *routine.Block) {
 
 func (f *Function) Poll(block *routine.Block) routine.Flow { return f.PollFunc(block) }
 
+func (f *Function) End(block *routine.Block) {
+	if f.EndFunc != nil {
+		f.EndFunc(block)
+	}
+}
+
+// SetName sets the Function's name, as returned by Name(). Built-in constructors name their Functions after
+// themselves (e.g. "JumpTo 'intro'"). SetName returns the Function for chaining.
+func (f *Function) SetName(name string) *Function {
+	f.name = name
+	return f
+}
+
+// Name returns the Function's name (see routine.ActionNameable), or "Function" if it hasn't been named.
+func (f *Function) Name() string {
+	if f.name == "" {
+		return "Function"
+	}
+	return f.name
+}
+
+// actionStart records the Block's running time and frame count (see Block.RunningTime()) at which an Action started,
+// along with the value it chose when it did, if any (see newChoosingFunction()).
+type actionStart struct {
+	time   time.Duration
+	frame  int
+	chosen any
+}
+
+// newTimedFunction creates a Function that measures how long it has been running in each Block it runs in, passing
+// the elapsed time and frame count to the given poll function. Unlike Block.CurrentActionElapsed(), this measures the
+// Function itself, even when it's nested in another Action (like a Gate or a NestedCollection).
+func newTimedFunction(poll func(block *routine.Block, elapsed time.Duration, frames int) routine.Flow) *Function {
+	return newChoosingFunction(nil, func(block *routine.Block, chosen any, elapsed time.Duration, frames int) routine.Flow {
+		return poll(block, elapsed, frames)
+	})
+}
+
+// newChoosingFunction is like newTimedFunction(), but also calls choose (if it's not nil) each time the Function
+// starts, passing the chosen value (like the length of a random wait) to the given poll function. Both the start and
+// the chosen value are stored in the Block (see Block.SetActionState()), so the Function can be shared between Blocks
+// without keeping Blocks that have since been removed reachable.
+func newChoosingFunction(choose func(block *routine.Block) any, poll func(block *routine.Block, chosen any, elapsed time.Duration, frames int) routine.Flow) *Function {
+
+	f := Captured(&Function{})
+
+	f.InitFunc = func(block *routine.Block) {
+		start := actionStart{time: block.RunningTime(), frame: block.RunningFrames()}
+		if choose != nil {
+			start.chosen = choose(block)
+		}
+		block.SetActionState(f, start)
+	}
+
+	f.PollFunc = func(block *routine.Block) routine.Flow {
+		start, _ := block.ActionState(f).(actionStart)
+		return poll(block, start.chosen, block.RunningTime()-start.time, block.RunningFrames()-start.frame)
+	}
+
+	return f
+
+}
+
+// quote formats the given value for use in an Action's name, wrapping strings in single quotes.
+func quote(value any) string {
+	if s, ok := value.(string); ok {
+		return "'" + s + "'"
+	}
+	return fmt.Sprint(value)
+}
+
+// quoteIDs formats the given Block IDs for use in an Action's name.
+func quoteIDs(ids []any) string {
+	if len(ids) == 0 {
+		return "(all)"
+	}
+	quoted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		quoted = append(quoted, quote(id))
+	}
+	return strings.Join(quoted, ", ")
+}
+
 // TimingPair represents an action to take after a specific duration of time
 // has passed.
 type TimingPair struct {
-	Duration   time.Duration
-	Function   func()
-	targetTime time.Time
+	Duration time.Duration
+	Function func()
 }
 
 // Timing is a timing Action, which executes a provided function when
-// some amount of time has elapsed.
+// some amount of time has elapsed. Like Wait, the time is measured using
+// the Block's time, so it doesn't progress while the Block is paused.
 type Timing struct {
-	pairs []TimingPair
-	index int
+	pairs         []TimingPair
+	index         int
+	pairStart     int           // The Block's frame count when the current pair started, in deterministic tick mode.
+	pairStartTime time.Duration // The Block's running time when the current pair started.
+	routine.Annotated
 }
 
 // NewTiming creates a new ActionTiming object. A ActionTiming object works with
 // TimingPairs, which indicate a function to execute after a specific duration
 // of time has passed.
 func NewTiming(timingPairs []TimingPair) *Timing {
-	return &Timing{
-		pairs: timingPairs,
-	}
+	return Captured(&Timing{
+		pairs: append([]TimingPair{}, timingPairs...),
+	})
 }
 
-func (t *Timing) Init() {
+func (t *Timing) Init(block *routine.Block) {
 	t.index = 0
+	t.pairStart = block.RunningFrames()
+	t.pairStartTime = block.RunningTime()
 }
 
 func (t *Timing) Poll(block *routine.Block) routine.Flow {
 
 	pair := &t.pairs[t.index]
 
-	if pair.targetTime.IsZero() {
-		pair.targetTime = time.Now().Add(pair.Duration)
+	elapsed := false
+
+	if r := block.Routine(); r.TickRate() > 0 {
+		elapsed = block.RunningFrames()-t.pairStart >= r.DurationToTicks(pair.Duration)
+	} else {
+		elapsed = block.RunningTime()-t.pairStartTime >= pair.Duration
 	}
 
-	if time.Now().After(pair.targetTime) {
+	if elapsed {
 		pair.Function()
+		t.pairStart = block.RunningFrames()
+		t.pairStartTime = block.RunningTime()
 
 		t.index++
 		if t.index >= len(t.pairs) {
@@ -138,12 +409,46 @@ func (t *Timing) Poll(block *routine.Block) routine.Flow {
 	return routine.FlowIdle
 }
 
+func (t *Timing) Name() string { return fmt.Sprintf("Timing(%d pairs)", len(t.pairs)) }
+
+// GateCompletion is simply a uint8, and represents what a Gate should do after one of its GateOptions finishes
+// executing its Actions.
+type GateCompletion uint8
+
+const (
+	// GateProceed means that the Block moves on to the Action following the Gate. This is the default.
+	GateProceed GateCompletion = iota
+	// GateReevaluate means that the Gate evaluates its GateOptions again (on the following frame), choosing a new one.
+	GateReevaluate
+	// GateJump means that the Block jumps to a Label (see GateOption.SetCompletionJump()).
+	GateJump
+)
+
+// GateMode is simply a uint8, and represents how a Gate chooses which of its GateOptions to run (see Gate.SetMode()).
+type GateMode uint8
+
+const (
+	// GateFirstMatch means that the Gate runs the first GateOption whose check function passes. This is the default.
+	GateFirstMatch GateMode = iota
+	// GateAllMatches means that the Gate runs every GateOption whose check function passes, in sequence, in the order
+	// they were added; the check functions are all evaluated when the Gate chooses. GateOptions with a nil check
+	// function act as an "else", running only if no other GateOption passes. Once the last GateOption finishes, the Gate
+	// follows its completion (see GateOption.SetCompletion()).
+	GateAllMatches
+)
+
 // GateOption represents a choice in a ActionGate Action.
 type GateOption struct {
-	CheckFunc func() bool
-	Active    bool
-	actions   []routine.Action
-	Index     int
+	CheckFunc   func() bool
+	Active      bool
+	actions     []routine.Action
+	Index       int
+	timesChosen int
+	completion  GateCompletion
+	jumpLabel   any
+	onEnter     func()
+	onExit      func()
+	entered     bool // Whether the option's current Action has been initialized and not ended yet.
 }
 
 // NewGateOption creates a new GateOption object, which represents a choice in an ActionGate. The checkFunc
@@ -155,25 +460,26 @@ type GateOption struct {
 // Block will move on to the next action after the Gate.
 func NewGateOption(checkFunc func() bool, Actions ...routine.Action) *GateOption {
 
-	newActions := []routine.Action{}
-
-	for _, c := range Actions {
-		if collection, ok := c.(*Collection); ok {
-			newActions = append(newActions, collection.actions...)
-		} else {
-			newActions = append(newActions, c)
-		}
-	}
-
 	return &GateOption{
 		CheckFunc: checkFunc,
-		actions:   newActions,
+		actions:   routine.FlattenActions(Actions...),
 	}
 }
 
 func (g *GateOption) Init(block *routine.Block) {
-	g.actions[0].Init(block)
 	g.Index = 0
+	if len(g.actions) > 0 {
+		g.actions[0].Init(block)
+		g.entered = true
+	}
+}
+
+// End ends the GateOption's current Action, if it's running and is an ActionEndable (see routine.ActionEndable).
+func (g *GateOption) End(block *routine.Block) {
+	if g.entered {
+		g.entered = false
+		endAction(g.actions[g.Index], block)
+	}
 }
 
 func (g *GateOption) Poll(block *routine.Block) routine.Flow {
@@ -186,19 +492,21 @@ func (g *GateOption) Poll(block *routine.Block) routine.Flow {
 
 	done := false
 
-	if result == routine.FlowNext {
+	if result == routine.FlowNext || result == routine.FlowSkip {
+		g.End(block)
 		g.Index++
 		if g.Index < len(g.actions) {
+			g.entered = true
 			g.actions[g.Index].Init(block)
 		} else {
-			g.actions[0].Init(block)
+			// The first Action is initialized again when the option is next chosen (see Gate.choose()).
 			g.Index = 0
 			done = true
 		}
 	}
 
-	if result == routine.FlowFinish {
-		return routine.FlowFinish
+	if result == routine.FlowFinish || result == routine.FlowFail {
+		return result
 	} else if done {
 		return routine.FlowNext
 	}
@@ -207,14 +515,59 @@ func (g *GateOption) Poll(block *routine.Block) routine.Flow {
 
 }
 
+// SetCompletion sets what the Gate does after the GateOption finishes executing its Actions. This allows for patterns
+// like re-deciding until a terminal option is chosen (by using GateReevaluate for the non-terminal options).
+// To jump to a Label, use GateOption.SetCompletionJump() instead.
+func (g *GateOption) SetCompletion(completion GateCompletion) *GateOption {
+	g.completion = completion
+	return g
+}
+
+// SetCompletionJump sets the GateOption to jump to the Label with the given ID after it finishes executing its Actions.
+func (g *GateOption) SetCompletionJump(labelID any) *GateOption {
+	g.completion = GateJump
+	g.jumpLabel = labelID
+	return g
+}
+
+// SetOnEnter sets a function to be called when the GateOption is chosen by its Gate (e.g. to highlight a UI element or
+// play a sound when a branch is entered). It returns the GateOption for chaining.
+func (g *GateOption) SetOnEnter(onEnter func()) *GateOption {
+	g.onEnter = onEnter
+	return g
+}
+
+// SetOnExit sets a function to be called when the GateOption finishes executing its Actions (including when one of
+// them finishes or fails the Block). It returns the GateOption for chaining.
+func (g *GateOption) SetOnExit(onExit func()) *GateOption {
+	g.onExit = onExit
+	return g
+}
+
+// Completion returns what the Gate does after the GateOption finishes executing its Actions.
+func (g *GateOption) Completion() GateCompletion {
+	return g.completion
+}
+
+// TimesChosen returns how many times the GateOption has been chosen by its Gate.
+func (g *GateOption) TimesChosen() int {
+	return g.timesChosen
+}
+
+func (g *GateOption) Name() string { return fmt.Sprintf("GateOption(%d actions)", len(g.actions)) }
+
 // Gate represents a gate, which allows for executing logic statements to determine
 // an execution path (one of the passed GateOptions). Once the logic statement is executed,
 // the gate is set until it is reset by revisiting the Action.
 type Gate struct {
 	Options     []*GateOption
 	ActiveEntry *GateOption
+	lastChosen  *GateOption
 	onIdle      func()
 	onChoose    func()
+	mode        GateMode
+	queue       []*GateOption
+	routine.Annotated
 }
 
 // NewGate creates a Gate action, which allows you to effectively choose one "route" or "choice"
@@ -222,9 +575,9 @@ type Gate struct {
 // Once one GateOption has been made active, it will stay active until the Gate runs
 // through all actions the GateOption might have.
 func NewGate(entries ...*GateOption) *Gate {
-	return &Gate{
+	return Captured(&Gate{
 		Options: entries,
-	}
+	})
 }
 
 // AddOption adds an option to the Gate action.
@@ -233,30 +586,83 @@ func (c *Gate) AddOption(option *GateOption) *Gate {
 	return c
 }
 
-func (c *Gate) Init(block *routine.Block) {
-	for _, entry := range c.Options {
-		if len(entry.actions) > 0 {
-			entry.actions[0].Init(block)
-		}
+// End ends the Action the active GateOption is running, if any (see routine.ActionEndable).
+func (c *Gate) End(block *routine.Block) {
+	if c.ActiveEntry != nil {
+		c.ActiveEntry.End(block)
 	}
+}
+
+// Init resets the Gate so that it chooses an option again. The options' Actions are only initialized once their option
+// is chosen, so options that aren't chosen have no side effects.
+func (c *Gate) Init(block *routine.Block) {
 	c.ActiveEntry = nil
+	c.queue = c.queue[:0]
 }
 
+func (c *Gate) Name() string { return fmt.Sprintf("Gate(%d options)", len(c.Options)) }
+
 func (c *Gate) Poll(block *routine.Block) routine.Flow {
 
 	if c.ActiveEntry != nil {
-		return c.ActiveEntry.Poll(block)
+
+		result := c.ActiveEntry.Poll(block)
+
+		if result != routine.FlowIdle && c.ActiveEntry.onExit != nil {
+			c.ActiveEntry.onExit()
+		}
+
+		if result == routine.FlowNext && len(c.queue) > 0 {
+			next := c.queue[0]
+			c.queue = c.queue[1:]
+			c.choose(next, block)
+			return routine.FlowIdle
+		}
+
+		if result == routine.FlowNext {
+
+			switch c.ActiveEntry.completion {
+			case GateReevaluate:
+				c.Init(block)
+				return routine.FlowIdle
+			case GateJump:
+				block.JumpTo(c.ActiveEntry.jumpLabel)
+			}
+
+		}
+
+		return result
+
 	} else {
 		if c.onIdle != nil {
 			c.onIdle()
 		}
-		for _, entry := range c.Options {
-			if entry.CheckFunc == nil || entry.CheckFunc() {
-				c.ActiveEntry = entry
-				if c.onChoose != nil {
-					c.onChoose()
+		if c.mode == GateAllMatches {
+			var fallback *GateOption
+			c.queue = c.queue[:0]
+			for _, entry := range c.Options {
+				if entry.CheckFunc == nil {
+					if fallback == nil {
+						fallback = entry
+					}
+				} else if entry.CheckFunc() {
+					c.queue = append(c.queue, entry)
+				}
+			}
+			if len(c.queue) == 0 && fallback != nil {
+				c.queue = append(c.queue, fallback)
+			}
+			if len(c.queue) > 0 {
+				next := c.queue[0]
+				c.queue = c.queue[1:]
+				c.choose(next, block)
+			}
+		} else {
+			for _, entry := range c.Options {
+				if entry.CheckFunc == nil || entry.CheckFunc() {
+					c.choose(entry, block)
+					break
 				}
-				break
 			}
 		}
 	}
@@ -265,6 +671,56 @@ func (c *Gate) Poll(block *routine.Block) routine.Flow {
 
 }
 
+func (c *Gate) choose(entry *GateOption, block *routine.Block) {
+	entry.Init(block)
+	c.ActiveEntry = entry
+	c.lastChosen = entry
+	entry.timesChosen++
+	if c.onChoose != nil {
+		c.onChoose()
+	}
+	if entry.onEnter != nil {
+		entry.onEnter()
+	}
+}
+
+// SetMode sets how the Gate chooses which of its GateOptions to run. It returns the Gate for chaining.
+func (c *Gate) SetMode(mode GateMode) *Gate {
+	c.mode = mode
+	return c
+}
+
+// Mode returns how the Gate chooses which of its GateOptions to run.
+func (c *Gate) Mode() GateMode {
+	return c.mode
+}
+
+// ChosenOption returns the GateOption most recently chosen by the Gate, or nil if no option has been chosen yet.
+// Unlike ActiveEntry, this isn't cleared when the Gate is revisited.
+func (c *Gate) ChosenOption() *GateOption {
+	return c.lastChosen
+}
+
+// Unchosen returns the GateOptions that have never been chosen by the Gate. This is useful to verify that all branches
+// of a conditional sequence are reachable.
+func (c *Gate) Unchosen() []*GateOption {
+	unchosen := []*GateOption{}
+	for _, option := range c.Options {
+		if option.timesChosen == 0 {
+			unchosen = append(unchosen, option)
+		}
+	}
+	return unchosen
+}
+
+// ResetHistory resets the Gate's history of which GateOptions were chosen (and how many times).
+func (c *Gate) ResetHistory() {
+	c.lastChosen = nil
+	for _, option := range c.Options {
+		option.timesChosen = 0
+	}
+}
+
 // SetOnIdle sets the idling function for the ActionGate - when this is set, this function will run
 // as long as a gate option isn't chosen.
 func (c *Gate) SetOnIdle(onIdle func()) *Gate {
@@ -291,24 +747,15 @@ type Collection struct {
 // A Collection by itself does nothing. Instead, the Actions that it is created with are
 // supplied in sequence to other Actions that take individual Actions.
 func NewCollection(actions ...routine.Action) *Collection {
-	collection := &Collection{}
-
-	newActions := []routine.Action{}
-	for _, c := range actions {
-		if collection, ok := c.(routine.ActionCollectionable); ok {
-			newActions = append(newActions, collection.Actions()...)
-		} else {
-			newActions = append(newActions, c)
-		}
+	return &Collection{
+		actions: routine.FlattenActions(actions...),
 	}
-	collection.actions = newActions
-
-	return collection
 }
 
 // AddAction allows you to add an Action to the Collection after creation.
+// As with NewCollection(), any ActionCollectionables passed are replaced with the Actions they contain.
 func (q *Collection) AddAction(action routine.Action) {
-	q.actions = append(q.actions, action)
+	q.actions = append(q.actions, routine.FlattenActions(action)...)
 }
 
 func (q *Collection) Init(block *routine.Block) {}
@@ -317,19 +764,209 @@ func (q *Collection) Poll(block *routine.Block) routine.Flow { return routine.Fl
 
 func (q *Collection) Actions() []routine.Action { return q.actions }
 
+// NestedCollection is a collection of Actions that, unlike a Collection, preserves its identity when added to a Block
+// or Gate - rather than being replaced by the Actions it contains, it runs them in sequence as a single Action.
+// Because of this, Labels within a NestedCollection can't be jumped to from the Block, and the Block's index stays on
+// the NestedCollection while any of its Actions are running.
+type NestedCollection struct {
+	actions []routine.Action
+	index   int
+	routine.Annotated
+}
+
+// NewNestedCollection creates a NestedCollection, which runs the given Actions in sequence as a single Action.
+// Any ActionCollectionables passed (like Collections) are replaced with the Actions they contain.
+func NewNestedCollection(actions ...routine.Action) *NestedCollection {
+	return Captured(&NestedCollection{
+		actions: routine.FlattenActions(actions...),
+	})
+}
+
+// AddAction allows you to add an Action to the NestedCollection after creation.
+func (n *NestedCollection) AddAction(action routine.Action) {
+	n.actions = append(n.actions, routine.FlattenActions(action)...)
+}
+
+// Children returns the Actions contained in the NestedCollection.
+func (n *NestedCollection) Children() []routine.Action { return n.actions }
+
+func (n *NestedCollection) Name() string {
+	return fmt.Sprintf("NestedCollection(%d actions)", len(n.actions))
+}
+
+func (n *NestedCollection) Init(block *routine.Block) {
+	n.index = 0
+	if len(n.actions) > 0 {
+		n.actions[0].Init(block)
+	}
+}
+
+// End ends the currently running Action in the NestedCollection, if it's an ActionEndable.
+func (n *NestedCollection) End(block *routine.Block) {
+	if n.index < len(n.actions) {
+		endAction(n.actions[n.index], block)
+	}
+}
+
+// endAction calls End() on the given Action if it's an ActionEndable.
+func endAction(action routine.Action, block *routine.Block) {
+	if endable, ok := action.(routine.ActionEndable); ok {
+		endable.End(block)
+	}
+}
+
+func (n *NestedCollection) Poll(block *routine.Block) routine.Flow {
+
+	for n.index < len(n.actions) {
+
+		result := n.actions[n.index].Poll(block)
+
+		if result != routine.FlowNext && result != routine.FlowSkip {
+			return result
+		}
+
+		endAction(n.actions[n.index], block)
+		n.index++
+
+		if n.index < len(n.actions) {
+			n.actions[n.index].Init(block)
+		}
+
+	}
+
+	return routine.FlowNext
+
+}
+
+// GroupWithDeadline is a NestedCollection that must finish within a deadline. If its Actions haven't finished by the
+// time the deadline passes, the rest of them are skipped and the Block moves on. This is useful for "optional flourish"
+// segments that must never delay the critical path of a scene. The deadline follows the Block's time (see
+// Block.RunningTime()).
+type GroupWithDeadline struct {
+	*NestedCollection
+	Deadline  time.Duration
+	onTimeout func(block *routine.Block)
+	timedOut  bool
+	startTime time.Duration // The Block's running time when the GroupWithDeadline started.
+}
+
+// NewGroupWithDeadline creates a GroupWithDeadline, which runs the given Actions in sequence as a single Action,
+// skipping the rest of them if they haven't finished within the given deadline.
+func NewGroupWithDeadline(deadline time.Duration, actions ...routine.Action) *GroupWithDeadline {
+	return Captured(&GroupWithDeadline{
+		NestedCollection: NewNestedCollection(actions...),
+		Deadline:         deadline,
+	})
+}
+
+// SetOnTimeout sets a function to be called when the GroupWithDeadline's deadline passes before its Actions have
+// finished (e.g. to snap an interrupted animation to its end state). It returns the GroupWithDeadline for chaining.
+func (g *GroupWithDeadline) SetOnTimeout(onTimeout func(block *routine.Block)) *GroupWithDeadline {
+	g.onTimeout = onTimeout
+	return g
+}
+
+// TimedOut returns if the GroupWithDeadline's Actions were skipped because its deadline passed the last time it ran.
+func (g *GroupWithDeadline) TimedOut() bool {
+	return g.timedOut
+}
+
+func (g *GroupWithDeadline) Name() string {
+	return fmt.Sprintf("GroupWithDeadline %s (%d actions)", g.Deadline, len(g.actions))
+}
+
+func (g *GroupWithDeadline) Init(block *routine.Block) {
+	g.timedOut = false
+	g.startTime = block.RunningTime()
+	g.NestedCollection.Init(block)
+}
+
+func (g *GroupWithDeadline) Poll(block *routine.Block) routine.Flow {
+
+	if block.RunningTime()-g.startTime >= g.Deadline {
+		g.timedOut = true
+		if g.onTimeout != nil {
+			g.onTimeout(block)
+		}
+		return routine.FlowNext
+	}
+
+	return g.NestedCollection.Poll(block)
+
+}
+
+// OnlyIf is an Action that runs a single child Action only if a condition passes when it starts, and otherwise skips it
+// (returning routine.FlowSkip, so the child isn't counted as executed). This is cleaner than a Gate for a single
+// optional step.
+type OnlyIf struct {
+	Condition func() bool
+	Child     routine.Action
+	decided   bool
+	skipped   bool
+	routine.Annotated
+}
+
+// NewOnlyIf creates an OnlyIf action that runs the given child Action if the condition returns true when the OnlyIf
+// starts, and skips it otherwise.
+func NewOnlyIf(condition func() bool, child routine.Action) *OnlyIf {
+	return Captured(&OnlyIf{
+		Condition: condition,
+		Child:     child,
+	})
+}
+
+// Skipped returns if the OnlyIf's child was skipped because its condition didn't pass the last time it ran.
+func (o *OnlyIf) Skipped() bool {
+	return o.skipped
+}
+
+func (o *OnlyIf) Name() string { return "OnlyIf " + routine.ActionName(o.Child) }
+
+func (o *OnlyIf) Init(block *routine.Block) {
+	o.decided = false
+	o.skipped = false
+}
+
+// End ends the child Action if it was started (see routine.ActionEndable).
+func (o *OnlyIf) End(block *routine.Block) {
+	if o.decided && !o.skipped {
+		endAction(o.Child, block)
+	}
+}
+
+func (o *OnlyIf) Poll(block *routine.Block) routine.Flow {
+
+	if !o.decided {
+		o.decided = true
+		if o.Condition != nil && !o.Condition() {
+			o.skipped = true
+			return routine.FlowSkip
+		}
+		o.Child.Init(block)
+	}
+
+	if o.skipped {
+		return routine.FlowSkip
+	}
+
+	return o.Child.Poll(block)
+
+}
+
 // Label doesn't do anything specifically, but rather simply makes it possible
 // for Blocks to jump to specific locations with Block.JumpTo(). This is internally
 // the same as calling Block.SetIndex(), but with the index of the Label action.
 type Label struct {
 	Label any
+	routine.Annotated
 }
 
 // NewLabel creates a ActionLabel with the specified ID at the given location in the
 // Block, enabling jumping to this point.
 func NewLabel(id any) *Label {
-	return &Label{
+	return Captured(&Label{
 		Label: id,
-	}
+	})
 }
 
 func (l *Label) Init(block *routine.Block) {}
@@ -338,6 +975,8 @@ func (l *Label) Poll(block *routine.Block) routine.Flow { return routine.FlowNex
 
 func (l *Label) ID() any { return l.Label }
 
+func (l *Label) Name() string { return "Label " + quote(l.Label) }
+
 // NewJumpTo creates a Function action that jumps the Block to the ActionLabel that has
 // the specified label ID.
 // If no Action with the label given is found, then the action will do nothing.
@@ -347,7 +986,37 @@ func NewJumpTo(label any) *Function {
 			block.JumpTo(label)
 			return routine.FlowNext
 		},
-	)
+	).SetName("JumpTo " + quote(label))
+}
+
+// NewJumpToBlockLabel creates a Function action that jumps the Block with the given ID to the Label with the given
+// label ID and runs it (see Routine.JumpTo()). The current Block is unaffected, unless it's the Block jumped to.
+func NewJumpToBlockLabel(blockID, labelID any) *Function {
+	return NewFunction(
+		func(block *routine.Block) routine.Flow {
+			block.Routine().JumpTo(blockID, labelID)
+			return routine.FlowNext
+		},
+	).SetName("JumpToBlockLabel " + quote(blockID) + " " + quote(labelID))
+}
+
+// NewFork creates a Function action that spawns a new anonymous Block containing the Actions returned by the given
+// function, running in parallel with the current Block, and then moves on immediately. This is useful for
+// fire-and-forget side sequences, like particle bursts timed relative to the main script. Spawned Blocks are transient,
+// removing themselves from the Routine once they finish (see Routine.RunTransient()).
+// The function is called each time a Block is spawned, so that each Block gets its own Actions; as a result, the
+// action can spawn a Block while a previously spawned one is still running (e.g. in a loop). For example:
+//
+//	actions.NewFork(func() []routine.Action {
+//		return []routine.Action{actions.NewWait(time.Second), actions.NewFunction(burst)}
+//	})
+func NewFork(build func() []routine.Action) *Function {
+	return NewFunction(
+		func(block *routine.Block) routine.Flow {
+			block.Routine().RunTransient(build()...)
+			return routine.FlowNext
+		},
+	).SetName("Fork")
 }
 
 // NewSwitchBlock creates a Function action that switches the routine to only activate blocks with
@@ -361,7 +1030,7 @@ func NewSwitchBlock(blockIDs ...any) *Function {
 			r.Run(blockIDs...)
 			return routine.FlowNext
 		},
-	)
+	).SetName("SwitchBlock " + quoteIDs(blockIDs))
 }
 
 // NewRunBlock creates a Function action that activates the specified blocks in the
@@ -373,7 +1042,7 @@ func NewRunBlock(blockIDs ...any) *Function {
 			block.Routine().Run(blockIDs...)
 			return routine.FlowNext
 		},
-	)
+	).SetName("RunBlock " + quoteIDs(blockIDs))
 }
 
 // NewPauseBlock creates a Function action that deactivates the specified blocks
@@ -385,7 +1054,7 @@ func NewPauseBlock(blockIDs ...any) *Function {
 			block.Routine().Pause(blockIDs...)
 			return routine.FlowNext
 		},
-	)
+	).SetName("PauseBlock " + quoteIDs(blockIDs))
 }
 
 // NewStopBlock creates a Function action that deactivates the specified blocks
@@ -397,7 +1066,19 @@ func NewStopBlock(blockIDs ...any) *Function {
 			block.Routine().Stop(blockIDs...)
 			return routine.FlowNext
 		},
-	)
+	).SetName("StopBlock " + quoteIDs(blockIDs))
+}
+
+// NewRestartBlock creates a Function action that restarts the specified blocks
+// in the currently running Routine. Any other blocks are unaffected.
+// If no block IDs are specified, all blocks are restarted.
+func NewRestartBlock(blockIDs ...any) *Function {
+	return NewFunction(
+		func(block *routine.Block) routine.Flow {
+			block.Routine().Restart(blockIDs...)
+			return routine.FlowNext
+		},
+	).SetName("RestartBlock " + quoteIDs(blockIDs))
 }
 
 // NewSetIndex creates a Function action that sets the index of the current block to the
@@ -409,7 +1090,7 @@ func NewSetIndex(index int) *Function {
 			block.SetIndex(index)
 			return routine.FlowNext
 		},
-	)
+	).SetName(fmt.Sprintf("SetIndex %d", index))
 }
 
 // NewFinish creates a Function action that simply returns routine.FlowFinish, indicating
@@ -419,7 +1100,45 @@ func NewFinish() *Function {
 		func(block *routine.Block) routine.Flow {
 			return routine.FlowFinish
 		},
-	)
+	).SetName("Finish")
+}
+
+// NewFinishIf creates a Function action that returns routine.FlowFinish if the given condition function returns true,
+// finishing the current Block. Otherwise, the Block simply moves on to the next Action.
+func NewFinishIf(condition func() bool) *Function {
+	return NewFunction(
+		func(block *routine.Block) routine.Flow {
+			if condition() {
+				return routine.FlowFinish
+			}
+			return routine.FlowNext
+		},
+	).SetName("FinishIf")
+}
+
+// NewFinishRoutineIf creates a Function action that gracefully finishes all Blocks in the current Routine (see
+// Routine.Finish()) if the given condition function returns true, finishing the current Block immediately. Otherwise,
+// the Block simply moves on to the next Action.
+func NewFinishRoutineIf(condition func() bool) *Function {
+	return NewFunction(
+		func(block *routine.Block) routine.Flow {
+			if condition() {
+				block.Routine().Finish()
+				return routine.FlowFinish
+			}
+			return routine.FlowNext
+		},
+	).SetName("FinishRoutineIf")
+}
+
+// NewRestart creates a Function action that simply returns routine.FlowRestartBlock, restarting the current Block
+// from its first Action and resetting its local state (its local Properties, result, Label crossings, and timers).
+func NewRestart() *Function {
+	return NewFunction(
+		func(block *routine.Block) routine.Flow {
+			return routine.FlowRestartBlock
+		},
+	).SetName("Restart")
 }
 
 // NewLoop creates a Function action that simply loops the current block's execution when it is executed.
@@ -427,5 +1146,5 @@ func NewLoop() *Function {
 	return NewFunction(func(block *routine.Block) routine.Flow {
 		block.SetIndex(0)
 		return routine.FlowNext
-	})
+	}).SetName("Loop")
 }
@@ -0,0 +1,380 @@
+package actions_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/actions"
+)
+
+// mark returns a Function action that records the Routine's time under the given name, and then moves on.
+func mark(r *routine.Routine, marks map[string]time.Duration, name string) *actions.Function {
+	return actions.NewFunction(func(block *routine.Block) routine.Flow {
+		marks[name] = r.Elapsed()
+		return routine.FlowNext
+	})
+}
+
+// runFor updates the Routine in steps of the given delta until the given amount of time has passed.
+func runFor(r *routine.Routine, delta, total time.Duration) {
+	for elapsed := time.Duration(0); elapsed < total; elapsed += delta {
+		r.UpdateWithDelta(delta)
+	}
+}
+
+func TestWaitNestedTimesItself(t *testing.T) {
+
+	const step = 10 * time.Millisecond
+	const wait = 100 * time.Millisecond
+
+	nested := func(r *routine.Routine, marks map[string]time.Duration) routine.Action {
+		return actions.NewNestedCollection(actions.NewWait(wait), mark(r, marks, "a"), actions.NewWait(wait), mark(r, marks, "b"))
+	}
+
+	gated := func(r *routine.Routine, marks map[string]time.Duration) routine.Action {
+		return actions.NewGate(actions.NewGateOption(nil, actions.NewWait(wait), mark(r, marks, "a"), actions.NewWait(wait), mark(r, marks, "b")))
+	}
+
+	for name, build := range map[string]func(*routine.Routine, map[string]time.Duration) routine.Action{"NestedCollection": nested, "Gate": gated} {
+
+		r := routine.New()
+		marks := map[string]time.Duration{}
+		r.Define("block", build(r, marks))
+		r.Run("block")
+
+		runFor(r, step, time.Second)
+
+		a, okA := marks["a"]
+		b, okB := marks["b"]
+		if !okA || !okB {
+			t.Fatalf("%s: marks weren't reached: %v", name, marks)
+		}
+		// A GateOption moves on to its next Action on the following frame, so the marks may trail by a step.
+		if b-a < wait || b-a > wait+step {
+			t.Errorf("%s: second Wait lasted %s, expected %s (marks: a@%s, b@%s)", name, b-a, wait, a, b)
+		}
+
+	}
+
+}
+
+func TestWaitPausedDoesNotElapse(t *testing.T) {
+
+	const step = 10 * time.Millisecond
+
+	r := routine.New()
+	marks := map[string]time.Duration{}
+	block := r.Define("block", actions.NewWait(100*time.Millisecond), mark(r, marks, "done"))
+	r.Run("block")
+
+	runFor(r, step, 50*time.Millisecond)
+	block.Pause()
+	runFor(r, step, time.Second)
+	block.Run()
+	runFor(r, step, 40*time.Millisecond)
+
+	if _, ok := marks["done"]; ok {
+		t.Fatalf("Wait finished while its Block was paused")
+	}
+
+	runFor(r, step, 20*time.Millisecond)
+
+	if _, ok := marks["done"]; !ok {
+		t.Fatalf("Wait didn't finish once its Block had been updated for its duration")
+	}
+
+}
+
+func TestCaptureSourcePerAction(t *testing.T) {
+
+	actions.SetCaptureSource(true)
+	defer actions.SetCaptureSource(false)
+
+	first := actions.NewWait(time.Second)
+	second := actions.NewNestedCollection(actions.NewLabel("a"))
+
+	firstSource, _ := actions.Annotations(first)[actions.AnnotationSource].(string)
+	secondSource, _ := actions.Annotations(second)[actions.AnnotationSource].(string)
+
+	if !strings.Contains(firstSource, "actions_test.go:") || !strings.Contains(secondSource, "actions_test.go:") {
+		t.Fatalf("sources weren't captured in the test file: %q, %q", firstSource, secondSource)
+	}
+	if firstSource == secondSource {
+		t.Fatalf("both Actions were given the same source %q", firstSource)
+	}
+
+	actions.SetCaptureSource(false)
+
+	if source, ok := actions.Annotations(actions.NewWait(time.Second))[actions.AnnotationSource]; ok {
+		t.Fatalf("source %v was captured while capture was disabled", source)
+	}
+
+}
+
+func TestWaitForRoutine(t *testing.T) {
+
+	intro := routine.New()
+	level := routine.New()
+
+	introDone := false
+	started := false
+
+	intro.Define("intro", actions.NewWaitUntil(func() bool { return introDone }))
+	level.Define("level", actions.NewWaitForRoutine(intro), actions.NewFunction(func(block *routine.Block) routine.Flow {
+		started = true
+		return routine.FlowIdle
+	}))
+
+	intro.Run()
+	level.Run()
+
+	for i := 0; i < 3; i++ {
+		intro.Update()
+		level.Update()
+	}
+
+	if started {
+		t.Fatalf("level started while the intro Routine was still running")
+	}
+
+	introDone = true
+	intro.Update()
+	level.Update()
+
+	if !started {
+		t.Fatalf("level didn't start once the intro Routine stopped running")
+	}
+
+}
+
+func TestWaitWakeTimeScaled(t *testing.T) {
+
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, scale := range []float64{0.5, 1, 2} {
+
+		clock := routine.NewManualClock(start)
+		r := routine.New()
+		r.SetClock(clock)
+		r.SetTimeScale(scale)
+
+		marks := map[string]time.Duration{}
+		r.Define("block", actions.NewWait(time.Second), mark(r, marks, "done"))
+		r.Run("block")
+		r.Update()
+
+		wake, ok := r.NextWakeTime()
+		if !ok {
+			t.Fatalf("scale %g: no wake time while idling on a Wait", scale)
+		}
+
+		expected := start.Add(time.Duration(float64(time.Second) / scale))
+		if !wake.Equal(expected) {
+			t.Fatalf("scale %g: wake time is %s after the start, expected %s", scale, wake.Sub(start), expected.Sub(start))
+		}
+
+		clock.Set(wake.Add(-time.Millisecond))
+		r.Update()
+		if _, ok := marks["done"]; ok {
+			t.Fatalf("scale %g: Wait finished before its wake time", scale)
+		}
+
+		clock.Set(wake)
+		r.Update()
+		if _, ok := marks["done"]; !ok {
+			t.Fatalf("scale %g: Wait didn't finish at its wake time", scale)
+		}
+
+	}
+
+	r := routine.New()
+	r.SetTimeScale(0)
+	r.Define("block", actions.NewWait(time.Second))
+	r.Run("block")
+	r.Update()
+
+	if _, ok := r.NextWakeTime(); ok {
+		t.Fatalf("a wake time was reported with a time scale of 0")
+	}
+
+}
+
+// initCounter returns a Function action that counts how many times it's initialized and then moves on.
+func initCounter(count *int) *actions.Function {
+	f := actions.NewFunction(func(block *routine.Block) routine.Flow { return routine.FlowNext })
+	f.InitFunc = func(block *routine.Block) { *count++ }
+	return f
+}
+
+func TestGateInitsOnlyChosenOption(t *testing.T) {
+
+	chosen, unchosen := 0, 0
+
+	r := routine.New()
+	r.Define("block", actions.NewGate(
+		actions.NewGateOption(func() bool { return false }, initCounter(&unchosen)),
+		actions.NewGateOption(nil, initCounter(&chosen)),
+	))
+	r.Run("block")
+	updateTimes(r, 5)
+
+	if unchosen != 0 {
+		t.Fatalf("unchosen option's Action was initialized %d times", unchosen)
+	}
+	if chosen != 1 {
+		t.Fatalf("chosen option's Action was initialized %d times, expected once", chosen)
+	}
+
+}
+
+func TestGateEmptyOption(t *testing.T) {
+
+	r := routine.New()
+	marks := map[string]time.Duration{}
+	r.Define("block", actions.NewGate(actions.NewGateOption(nil)), mark(r, marks, "after"))
+	r.Run("block")
+	updateTimes(r, 3)
+
+	if _, ok := marks["after"]; !ok {
+		t.Fatalf("Block didn't move past a Gate with an empty option")
+	}
+
+}
+
+// updateTimes updates the Routine the given number of times.
+func updateTimes(r *routine.Routine, updates int) {
+	for i := 0; i < updates; i++ {
+		r.Update()
+	}
+}
+
+func TestGateEndsOptionActions(t *testing.T) {
+
+	ended := []string{}
+	endable := func(name string, flow routine.Flow) *actions.Function {
+		f := actions.NewFunction(func(block *routine.Block) routine.Flow { return flow })
+		f.EndFunc = func(block *routine.Block) { ended = append(ended, name) }
+		return f
+	}
+
+	r := routine.New()
+	block := r.Define("block", actions.NewGate(actions.NewGateOption(nil, endable("a", routine.FlowNext), endable("b", routine.FlowIdle))))
+	r.Run("block")
+	updateTimes(r, 3)
+
+	if len(ended) != 1 || ended[0] != "a" {
+		t.Fatalf("ended %v after advancing within the option, expected [a]", ended)
+	}
+
+	block.Stop()
+
+	if len(ended) != 2 || ended[1] != "b" {
+		t.Fatalf("ended %v after stopping the Block, expected [a b]", ended)
+	}
+
+}
+
+func TestForkOverlapping(t *testing.T) {
+
+	const step = 10 * time.Millisecond
+
+	r := routine.New()
+	finished := []time.Duration{}
+
+	fork := actions.NewFork(func() []routine.Action {
+		// A NestedCollection keeps its progress on itself, so it can't be shared between the spawned Blocks.
+		return []routine.Action{actions.NewNestedCollection(
+			actions.NewWait(100*time.Millisecond),
+			actions.NewFunction(func(block *routine.Block) routine.Flow {
+				finished = append(finished, r.Elapsed())
+				return routine.FlowNext
+			}),
+		)}
+	})
+
+	// Forks every 50ms, so that each fork is spawned while the previous one is still waiting.
+	r.Define("block", fork, actions.NewWait(50*time.Millisecond), fork)
+	r.Run("block")
+
+	runFor(r, step, 300*time.Millisecond)
+
+	if len(finished) != 2 {
+		t.Fatalf("%d forks finished, expected 2", len(finished))
+	}
+	if gap := finished[1] - finished[0]; gap < 50*time.Millisecond || gap > 50*time.Millisecond+step {
+		t.Fatalf("forks finished %s apart, expected 50ms", gap)
+	}
+
+}
+
+func TestWaitTickMode(t *testing.T) {
+
+	r := routine.New()
+	r.SetTickRate(3)
+	// Neither the wall clock nor the time scale affect tick mode.
+	r.SetClock(routine.NewManualClock(time.Time{}))
+	r.SetTimeScale(2)
+
+	marks := map[string]time.Duration{}
+	r.Define("block", mark(r, marks, "start"), actions.NewWait(time.Second), mark(r, marks, "end"))
+	r.Run("block")
+
+	updates := 0
+	for ; updates < 10; updates++ {
+		if _, ok := marks["end"]; ok {
+			break
+		}
+		r.Update()
+	}
+
+	if waited := marks["end"] - marks["start"]; waited != time.Second {
+		t.Fatalf("Wait lasted %s of tick time, expected exactly 1s", waited)
+	}
+	if updates != 4 {
+		t.Fatalf("Wait took %d updates at 3 ticks per second, expected the starting update and 3 ticks", updates)
+	}
+
+}
+
+func TestTimeScale(t *testing.T) {
+
+	const step = 10 * time.Millisecond
+
+	r := routine.New()
+	r.SetTimeScale(2)
+
+	finished := false
+	r.Define("block", actions.NewWait(100*time.Millisecond), actions.NewFunction(func(block *routine.Block) routine.Flow {
+		finished = true
+		return routine.FlowIdle
+	}))
+	r.Run("block")
+
+	// The Wait starts on the first update, and then counts 20ms of scaled time per update.
+	runFor(r, step, 50*time.Millisecond)
+	if finished {
+		t.Fatalf("Wait finished after 80ms of scaled time")
+	}
+
+	runFor(r, step, 10*time.Millisecond)
+	if !finished {
+		t.Fatalf("Wait didn't finish after 100ms of scaled time")
+	}
+
+	r = routine.New()
+	r.Define("ramp", actions.NewRampTimeScale(0, 100*time.Millisecond))
+	r.Run("ramp")
+
+	runFor(r, step, 50*time.Millisecond)
+	if scale := r.TimeScale(); scale <= 0 || scale >= 1 {
+		t.Fatalf("time scale was %g halfway through the ramp", scale)
+	}
+
+	runFor(r, step, 60*time.Millisecond)
+	if scale := r.TimeScale(); scale != 0 || r.Running("ramp") {
+		t.Fatalf("time scale was %g after the ramp, which should have finished on schedule", scale)
+	}
+
+}
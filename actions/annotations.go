@@ -0,0 +1,62 @@
+package actions
+
+import (
+	"github.com/solarlune/routine"
+)
+
+// Annotate attaches arbitrary metadata (e.g. an author's comment, the source file and line, or a script ID) to the
+// given Action, returning the Action so that it can be used inline in a Block definition. Annotating the same Action
+// multiple times merges the metadata, with later values overwriting earlier ones. Tooling can then retrieve the metadata
+// with Annotations() to point back to where an Action was authored; it's also included in routinetest traces, in the
+// errors Blocks fail with, and in graph documents and their validation problems.
+// The metadata is stored on the Action itself, so only Actions that are routine.ActionAnnotatables (like all of the
+// Actions in this package; custom Actions can embed routine.Annotated) can be annotated - other Actions are returned
+// unchanged.
+func Annotate(a routine.Action, meta map[string]any) routine.Action {
+
+	annotatable, ok := a.(routine.ActionAnnotatable)
+	if !ok {
+		return a
+	}
+
+	existing := annotatable.Annotations()
+
+	merged := make(map[string]any, len(existing)+len(meta))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range meta {
+		merged[k] = v
+	}
+
+	annotatable.SetAnnotations(merged)
+
+	return a
+
+}
+
+// Annotations returns a copy of the metadata attached to the given Action through Annotate(), or nil if the Action
+// has no annotations.
+func Annotations(a routine.Action) map[string]any {
+
+	existing := routine.ActionAnnotations(a)
+	if existing == nil {
+		return nil
+	}
+
+	meta := make(map[string]any, len(existing))
+	for k, v := range existing {
+		meta[k] = v
+	}
+	return meta
+
+}
+
+// ClearAnnotations removes any metadata attached to the given Actions.
+func ClearAnnotations(actions ...routine.Action) {
+	for _, a := range actions {
+		if annotatable, ok := a.(routine.ActionAnnotatable); ok {
+			annotatable.SetAnnotations(nil)
+		}
+	}
+}
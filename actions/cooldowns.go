@@ -0,0 +1,66 @@
+package actions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/solarlune/routine"
+)
+
+// IfOffCooldown is a NestedCollection that only runs if a named cooldown in the Routine's cooldown registry (see
+// Routine.Cooldowns()) is ready, consuming the cooldown when it does. If the cooldown isn't ready, its Actions are
+// skipped and the Block moves on.
+type IfOffCooldown struct {
+	*NestedCollection
+	Cooldown any
+	Duration time.Duration
+	decided  bool
+	skipped  bool
+}
+
+// NewIfOffCooldown creates an IfOffCooldown, which runs the given Actions in sequence as a single Action if the
+// cooldown with the given name is ready, starting it for the given duration. Because the cooldown is shared across the
+// Routine's Blocks, this can be used to keep several Blocks from doing the same thing too often:
+//
+//	r.Define("guard-bark",
+//		actions.NewWaitUntil(playerSpotted),
+//		actions.NewIfOffCooldown("global_bark_cooldown", 10*time.Second, bark),
+//		actions.NewLoop(),
+//	)
+func NewIfOffCooldown(name any, duration time.Duration, actions ...routine.Action) *IfOffCooldown {
+	return Captured(&IfOffCooldown{
+		NestedCollection: NewNestedCollection(actions...),
+		Cooldown:         name,
+		Duration:         duration,
+	})
+}
+
+// Skipped returns if the IfOffCooldown's Actions were skipped because its cooldown wasn't ready the last time it ran.
+func (c *IfOffCooldown) Skipped() bool {
+	return c.skipped
+}
+
+func (c *IfOffCooldown) Name() string {
+	return fmt.Sprintf("IfOffCooldown %s %s (%d actions)", quote(c.Cooldown), c.Duration, len(c.actions))
+}
+
+func (c *IfOffCooldown) Init(block *routine.Block) {
+	c.decided = false
+	c.skipped = false
+	c.NestedCollection.Init(block)
+}
+
+func (c *IfOffCooldown) Poll(block *routine.Block) routine.Flow {
+
+	if !c.decided {
+		c.decided = true
+		c.skipped = !block.Routine().Cooldowns().Consume(c.Cooldown, c.Duration)
+	}
+
+	if c.skipped {
+		return routine.FlowNext
+	}
+
+	return c.NestedCollection.Poll(block)
+
+}
@@ -0,0 +1,112 @@
+package actions
+
+import (
+	"sort"
+	"time"
+
+	"github.com/solarlune/routine"
+)
+
+// Easing is the name of an easing function, used to shape the segments of a curve (see NewCurve()). Easings are
+// named with strings so that curves can be authored in data files.
+type Easing string
+
+const (
+	// EaseLinear interpolates between two points at a constant rate. This is the default.
+	EaseLinear Easing = "linear"
+	// EaseIn starts slowly and speeds up towards the next point.
+	EaseIn Easing = "in"
+	// EaseOut starts quickly and slows down towards the next point.
+	EaseOut Easing = "out"
+	// EaseInOut starts and ends slowly, moving quickest halfway between the points.
+	EaseInOut Easing = "in-out"
+	// EaseStep holds the value of a point until the next point is reached.
+	EaseStep Easing = "step"
+)
+
+// Apply eases the given value (ranging from 0 to 1). Unknown easings are treated as EaseLinear.
+func (e Easing) Apply(t float64) float64 {
+	switch e {
+	case EaseIn:
+		return t * t
+	case EaseOut:
+		return t * (2 - t)
+	case EaseInOut:
+		return smoothStep(t)
+	case EaseStep:
+		if t >= 1 {
+			return 1
+		}
+		return 0
+	}
+	return t
+}
+
+// CurvePoint represents a keyframe in a curve (see NewCurve()).
+type CurvePoint struct {
+	Time   float64 // The time of the point, normalized over the curve's duration (ranging from 0 to 1)
+	Value  float64 // The value of the curve at the point
+	Easing Easing  // The easing of the segment from this point to the next one
+}
+
+// evaluateCurve returns the value of the curve formed by the given points (sorted by time) at the given normalized
+// time.
+func evaluateCurve(points []CurvePoint, t float64) float64 {
+
+	if len(points) == 0 {
+		return 0
+	}
+
+	if t <= points[0].Time {
+		return points[0].Value
+	}
+
+	for i := 0; i < len(points)-1; i++ {
+
+		start, end := points[i], points[i+1]
+
+		if t >= end.Time {
+			continue
+		}
+
+		u := 1.0
+		if end.Time > start.Time {
+			u = (t - start.Time) / (end.Time - start.Time)
+		}
+
+		return start.Value + (end.Value-start.Value)*start.Easing.Apply(u)
+
+	}
+
+	return points[len(points)-1].Value
+
+}
+
+// NewCurve creates a Function action that animates the property under the given key in the Block's Properties along
+// the curve formed by the given points over the given duration, and then moves on once the curve is complete, after
+// setting the property to the value of the curve's end. The property is set to a float64 every poll. Before the first
+// point's time and after the last point's, the curve holds the value of the first and last points, respectively.
+// Like NewWaitWithProgress(), the curve follows the Block's time (see Block.RunningTime()).
+func NewCurve(key any, points []CurvePoint, duration time.Duration) *Function {
+
+	sorted := append([]CurvePoint{}, points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time < sorted[j].Time })
+
+	return newTimedFunction(func(block *routine.Block, elapsed time.Duration, frames int) routine.Flow {
+
+		t := 1.0
+		if duration > 0 {
+			t = float64(elapsed) / float64(duration)
+		}
+
+		if t >= 1 {
+			block.Properties().Set(key, evaluateCurve(sorted, 1))
+			return routine.FlowNext
+		}
+
+		block.Properties().Set(key, evaluateCurve(sorted, t))
+		return routine.FlowIdle
+
+	}).SetName("Curve " + quote(key) + " " + duration.String())
+
+}
@@ -0,0 +1,75 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/solarlune/routine"
+)
+
+// SceneDirector represents an engine-specific adapter for common cutscene commands, allowing cutscenes to be scripted
+// in an engine-agnostic way. Each function starts the command and returns a completion predicate, which should return
+// true once the command has finished (e.g. once the camera or actor has arrived at its target).
+type SceneDirector interface {
+	MoveTo(target any) (done func() bool)
+	LookAt(target any) (done func() bool)
+	FadeTo(alpha float64) (done func() bool)
+}
+
+// NewDirect creates a Function action that starts a command when it is first polled by calling the provided function,
+// and then idles until the completion predicate the function returns reports that the command is done.
+// If the function returns a nil predicate, the action moves on immediately.
+// NewDirect takes a function that starts the command rather than the completion predicate itself (as in
+// NewDirect(director.MoveTo(target))), because calling director.MoveTo() while defining the Block would start the move
+// right away, rather than when the Block reaches the action (and only once, rather than each time it does). The command
+// is wrapped in a function literal instead, or NewMoveTo(), NewLookAt() or NewFadeTo() can be used:
+//
+//	actions.NewDirect(func() func() bool { return director.MoveTo(target) })
+//	actions.NewMoveTo(director, target) // Equivalent
+func NewDirect(command func() (done func() bool)) *Function {
+
+	var done func() bool
+	started := false
+
+	f := NewFunction(func(block *routine.Block) routine.Flow {
+
+		if !started {
+			done = command()
+			started = true
+		}
+
+		if done == nil || done() {
+			return routine.FlowNext
+		}
+
+		return routine.FlowIdle
+
+	})
+
+	f.InitFunc = func(block *routine.Block) {
+		started = false
+		done = nil
+	}
+
+	return f.SetName("Direct")
+
+}
+
+// NewMoveTo creates a Function action that calls director.MoveTo() with the given target, and then idles until the move is done.
+func NewMoveTo(director SceneDirector, target any) *Function {
+	return NewDirect(func() func() bool { return director.MoveTo(target) }).
+		SetName("MoveTo " + quote(target))
+}
+
+// NewLookAt creates a Function action that calls director.LookAt() with the given target, and then idles until the
+// look is done.
+func NewLookAt(director SceneDirector, target any) *Function {
+	return NewDirect(func() func() bool { return director.LookAt(target) }).
+		SetName("LookAt " + quote(target))
+}
+
+// NewFadeTo creates a Function action that calls director.FadeTo() with the given alpha, and then idles until the
+// fade is done.
+func NewFadeTo(director SceneDirector, alpha float64) *Function {
+	return NewDirect(func() func() bool { return director.FadeTo(alpha) }).
+		SetName(fmt.Sprintf("FadeTo %v", alpha))
+}
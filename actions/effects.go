@@ -0,0 +1,173 @@
+package actions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/solarlune/routine"
+)
+
+// smoothStep eases the given value (ranging from 0 to 1) in and out.
+func smoothStep(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+// NewFadeIn creates a Function action that fades in over the given duration, calling apply every poll with an alpha
+// value that eases smoothly from 0 to 1. The action moves on once the fade is complete, after calling apply with an
+// alpha of exactly 1.
+func NewFadeIn(duration time.Duration, apply func(alpha float64)) *Function {
+	return NewWaitWithProgress(duration, func(t float64) {
+		apply(smoothStep(t))
+	}).SetName("FadeIn " + duration.String())
+}
+
+// NewFadeOut creates a Function action that fades out over the given duration, calling apply every poll with an alpha
+// value that eases smoothly from 1 to 0. The action moves on once the fade is complete, after calling apply with an
+// alpha of exactly 0.
+func NewFadeOut(duration time.Duration, apply func(alpha float64)) *Function {
+	return NewWaitWithProgress(duration, func(t float64) {
+		apply(1 - smoothStep(t))
+	}).SetName("FadeOut " + duration.String())
+}
+
+// NewShake creates a Function action that sets the target value to damped, smoothly interpolated random noise for the
+// given duration, which can be used for screen-shake or rumble effects. The noise ranges between -amplitude and
+// amplitude, changes direction roughly frequency times per second, and is damped linearly to 0 over the duration. The
+// noise is drawn from the Block's random source (see Block.Rand()), so it's deterministic when the Routine is seeded.
+// The shake follows the Block's time (see Block.RunningTime()), so it pauses along with the Block. Once the duration
+// has elapsed, the target is set to 0 and the action moves on.
+func NewShake(target *float64, amplitude, frequency float64, duration time.Duration) *Function {
+
+	samples := []float64{0}
+
+	f := newTimedFunction(func(block *routine.Block, elapsed time.Duration, frames int) routine.Flow {
+
+		if elapsed >= duration {
+			*target = 0
+			return routine.FlowNext
+		}
+
+		t := elapsed.Seconds() * frequency
+		i := int(t)
+
+		for len(samples) < i+2 {
+			samples = append(samples, block.Rand().Float64()*2-1)
+		}
+
+		noise := samples[i] + (samples[i+1]-samples[i])*smoothStep(t-float64(i))
+		damping := 1 - float64(elapsed)/float64(duration)
+
+		*target = noise * amplitude * damping
+
+		return routine.FlowIdle
+
+	})
+
+	start := f.InitFunc
+	f.InitFunc = func(block *routine.Block) {
+		start(block)
+		samples = samples[:1]
+	}
+
+	return f.SetName("Shake " + duration.String())
+
+}
+
+// NewSetTimeScale creates a Function action that sets the time scale of the Block's Routine to the given value
+// (see Routine.SetTimeScale()), and then moves on.
+func NewSetTimeScale(scale float64) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		block.Routine().SetTimeScale(scale)
+		return routine.FlowNext
+	}).SetName(fmt.Sprintf("SetTimeScale %v", scale))
+}
+
+// timeScaleRamp is the state of a RampTimeScale action in a Block.
+type timeScaleRamp struct {
+	from    float64       // The time scale when the ramp started.
+	elapsed time.Duration // The unscaled time elapsed since the ramp started.
+	polled  bool          // Whether the ramp has been polled since it started.
+}
+
+// NewRampTimeScale creates a Function action that smoothly changes the time scale of the Block's Routine from its
+// value when the action starts to the given one over the given duration, and then moves on. The duration is measured
+// in unscaled time, so ramping the time scale down to 0 still completes on schedule.
+func NewRampTimeScale(to float64, over time.Duration) *Function {
+
+	f := Captured(&Function{})
+
+	f.InitFunc = func(block *routine.Block) {
+		block.SetActionState(f, &timeScaleRamp{from: block.Routine().TimeScale()})
+	}
+
+	f.PollFunc = func(block *routine.Block) routine.Flow {
+
+		r := block.Routine()
+		ramp := block.ActionState(f).(*timeScaleRamp)
+
+		if ramp.polled {
+			ramp.elapsed += r.UnscaledDeltaTime()
+		}
+		ramp.polled = true
+
+		if ramp.elapsed >= over {
+			r.SetTimeScale(to)
+			return routine.FlowNext
+		}
+
+		t := float64(ramp.elapsed) / float64(over)
+		r.SetTimeScale(ramp.from + (to-ramp.from)*t)
+		return routine.FlowIdle
+
+	}
+
+	return f.SetName(fmt.Sprintf("RampTimeScale %v over %s", to, over))
+
+}
+
+// NewFreezeOthers creates a Function action that pauses all other running Blocks in the Routine for the given duration
+// (e.g. for a hit-stop or a dramatic pause), and then resumes them and moves on. Only the Blocks that were running when
+// the freeze started are resumed. The duration follows the Block's time (see Block.RunningTime()). If the Block moves
+// off of the action before the freeze is over (e.g. because it's stopped or jumps elsewhere), the frozen Blocks are
+// resumed then.
+func NewFreezeOthers(duration time.Duration) *Function {
+
+	frozen := []*routine.Block{}
+
+	resume := func(block *routine.Block) {
+		for _, other := range frozen {
+			other.Run()
+		}
+		frozen = frozen[:0]
+	}
+
+	f := newTimedFunction(func(block *routine.Block, elapsed time.Duration, frames int) routine.Flow {
+
+		if elapsed < duration {
+			return routine.FlowIdle
+		}
+
+		resume(block)
+
+		return routine.FlowNext
+
+	})
+
+	f.EndFunc = resume
+
+	start := f.InitFunc
+	f.InitFunc = func(block *routine.Block) {
+		start(block)
+		frozen = frozen[:0]
+		block.Routine().ForEachBlock(func(other *routine.Block) bool {
+			if other != block && other.Running() {
+				other.Pause()
+				frozen = append(frozen, other)
+			}
+			return true
+		})
+	}
+
+	return f.SetName("FreezeOthers " + duration.String())
+
+}
@@ -0,0 +1,130 @@
+package actions
+
+import (
+	"sync"
+
+	"github.com/solarlune/routine"
+)
+
+// EventBus is a simple publish / subscribe event bus, decoupling Routines from each other and from game systems
+// that emit events (e.g. trigger volumes or quest updates). An EventBus is safe to use from multiple goroutines.
+type EventBus struct {
+	mutex       sync.Mutex
+	topics      map[any]*eventTopic
+	nextHandler int
+}
+
+type eventTopic struct {
+	count    int
+	payload  any
+	handlers []eventHandler // In the order they were subscribed.
+}
+
+type eventHandler struct {
+	id      int
+	handler func(payload any)
+}
+
+// NewEventBus creates a new EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{
+		topics: map[any]*eventTopic{},
+	}
+}
+
+func (e *EventBus) topic(topic any) *eventTopic {
+	t, ok := e.topics[topic]
+	if !ok {
+		t = &eventTopic{}
+		e.topics[topic] = t
+	}
+	return t
+}
+
+// Publish publishes an event to the given topic with the given payload, calling any handlers subscribed to the topic
+// (in the order they were subscribed) and allowing any Actions waiting on the topic to continue.
+func (e *EventBus) Publish(topic any, payload any) {
+
+	e.mutex.Lock()
+	t := e.topic(topic)
+	t.count++
+	t.payload = payload
+	handlers := make([]func(payload any), 0, len(t.handlers))
+	for _, h := range t.handlers {
+		handlers = append(handlers, h.handler)
+	}
+	e.mutex.Unlock()
+
+	for _, h := range handlers {
+		h(payload)
+	}
+
+}
+
+// Subscribe registers a handler function to be called whenever an event is published to the given topic.
+// Subscribe returns a function that unsubscribes the handler when called.
+func (e *EventBus) Subscribe(topic any, handler func(payload any)) (unsubscribe func()) {
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	id := e.nextHandler
+	e.nextHandler++
+	t := e.topic(topic)
+	t.handlers = append(t.handlers, eventHandler{id: id, handler: handler})
+
+	return func() {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		for i, h := range t.handlers {
+			if h.id == id {
+				t.handlers = append(t.handlers[:i], t.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+
+}
+
+// PublishCount returns how many events have been published to the given topic, along with the most recent event's payload.
+func (e *EventBus) PublishCount(topic any) (int, any) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	t := e.topic(topic)
+	return t.count, t.payload
+}
+
+// NewWaitForEvent creates a Function action that idles until an event is published to the given topic on the bus
+// after the action has started. The payload of the event is then set as the Block's result (see Block.LastResult()).
+// If multiple events are published to the topic between polls, the most recent payload is used. The number of events
+// published when the action starts is stored per Block, so the action can be shared between Blocks.
+func NewWaitForEvent(bus *EventBus, topic any) *Function {
+
+	f := Captured(&Function{})
+
+	f.InitFunc = func(block *routine.Block) {
+		startCount, _ := bus.PublishCount(topic)
+		block.SetActionState(f, startCount)
+	}
+
+	f.PollFunc = func(block *routine.Block) routine.Flow {
+		startCount, _ := block.ActionState(f).(int)
+		if count, payload := bus.PublishCount(topic); count > startCount {
+			block.SetResult(payload)
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	}
+
+	return f.SetName("WaitForEvent " + quote(topic))
+
+}
+
+// NewPublish creates a Function action that publishes an event to the given topic on the bus with the given payload,
+// and then moves on.
+func NewPublish(bus *EventBus, topic any, payload any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		bus.Publish(topic, payload)
+		return routine.FlowNext
+	}).SetName("Publish " + quote(topic))
+}
@@ -0,0 +1,64 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/solarlune/routine"
+)
+
+// NewCountUpTo creates a Function action that counts how many times it has been polled, storing the count as an int
+// in the Routine's Properties under the given key. It idles until it has been polled n times in total (across any
+// number of runs of the Block), and then moves on (which it continues to do immediately afterwards, until the count is
+// reset by deleting or changing the property).
+func NewCountUpTo(key any, n int) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+
+		props := block.Routine().Properties()
+
+		count, _ := props.Get(key).(int)
+
+		if count >= n {
+			return routine.FlowNext
+		}
+
+		count++
+		props.Set(key, count)
+
+		if count >= n {
+			return routine.FlowNext
+		}
+
+		return routine.FlowIdle
+
+	}).SetName(fmt.Sprintf("CountUpTo %s %d", quote(key), n))
+}
+
+// NewSetFlag creates a Function action that sets a flag (a bool set to true) in the Routine's Properties under the
+// given key, and then moves on.
+func NewSetFlag(key any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		block.Routine().Properties().Set(key, true)
+		return routine.FlowNext
+	}).SetName("SetFlag " + quote(key))
+}
+
+// NewClearFlag creates a Function action that clears a flag (setting it to false) in the Routine's Properties under
+// the given key, and then moves on.
+func NewClearFlag(key any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		block.Routine().Properties().Set(key, false)
+		return routine.FlowNext
+	}).SetName("ClearFlag " + quote(key))
+}
+
+// NewWaitForFlag creates a Function action that idles until the flag with the given key is set (i.e. the property
+// is a bool set to true) in the Routine's Properties. As the flag is stored in the Routine's Properties, it can be
+// set by another Block.
+func NewWaitForFlag(key any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		if set, _ := block.Routine().Properties().Get(key).(bool); set {
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	}).SetName("WaitForFlag " + quote(key))
+}
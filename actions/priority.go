@@ -0,0 +1,108 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/solarlune/routine"
+)
+
+// PriorityEntry represents an entry in a SelectPriority Action: a sequence of Actions that runs while its Condition
+// passes and no higher-priority entry's Condition does. A nil Condition always passes, which can be used as a
+// fallback entry at the end of a SelectPriority.
+type PriorityEntry struct {
+	Condition func() bool
+	Actions   []routine.Action
+}
+
+// NewPriorityEntry creates a PriorityEntry with the given condition and Actions.
+func NewPriorityEntry(condition func() bool, actions ...routine.Action) PriorityEntry {
+	return PriorityEntry{Condition: condition, Actions: actions}
+}
+
+// SelectPriority is a reactive priority selector. Unlike a Gate, which chooses a GateOption once and then runs it until
+// it's done, a SelectPriority evaluates its entries' conditions from the top down every time it's polled, and runs the
+// first entry whose condition passes. If a higher-priority entry's condition starts passing while a lower-priority one
+// is running, the lower-priority entry is aborted (and starts from the beginning if it's chosen again).
+//
+// When the running entry finishes its Actions, the Block moves on to the Action following the SelectPriority. While no
+// entry's condition passes, the SelectPriority idles.
+type SelectPriority struct {
+	entries     []priorityEntry
+	activeIndex int
+	routine.Annotated
+}
+
+type priorityEntry struct {
+	condition func() bool
+	actions   *NestedCollection
+}
+
+// NewSelectPriority creates a SelectPriority Action with the given entries, in order of decreasing priority.
+func NewSelectPriority(entries ...PriorityEntry) *SelectPriority {
+	s := &SelectPriority{activeIndex: -1}
+	for _, entry := range entries {
+		s.entries = append(s.entries, priorityEntry{
+			condition: entry.Condition,
+			actions:   NewNestedCollection(entry.Actions...),
+		})
+	}
+	return Captured(s)
+}
+
+// ActiveIndex returns the index of the entry the SelectPriority is currently running, or -1 if no entry is running.
+func (s *SelectPriority) ActiveIndex() int {
+	return s.activeIndex
+}
+
+func (s *SelectPriority) Name() string {
+	return fmt.Sprintf("SelectPriority(%d entries)", len(s.entries))
+}
+
+func (s *SelectPriority) Init(block *routine.Block) {
+	s.activeIndex = -1
+}
+
+// End ends the running entry's current Action, if any (see routine.ActionEndable).
+func (s *SelectPriority) End(block *routine.Block) {
+	if s.activeIndex >= 0 {
+		s.entries[s.activeIndex].actions.End(block)
+		s.activeIndex = -1
+	}
+}
+
+func (s *SelectPriority) Poll(block *routine.Block) routine.Flow {
+
+	chosen := -1
+
+	for i, entry := range s.entries {
+		if entry.condition == nil || entry.condition() {
+			chosen = i
+			break
+		}
+	}
+
+	if chosen != s.activeIndex {
+		s.End(block)
+	}
+
+	if chosen < 0 {
+		return routine.FlowIdle
+	}
+
+	entry := s.entries[chosen]
+
+	if chosen != s.activeIndex {
+		s.activeIndex = chosen
+		entry.actions.Init(block)
+	}
+
+	result := entry.actions.Poll(block)
+
+	// Otherwise, the entry's Actions are ended along with the SelectPriority.
+	if result == routine.FlowNext {
+		s.activeIndex = -1
+	}
+
+	return result
+
+}
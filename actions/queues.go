@@ -0,0 +1,33 @@
+package actions
+
+import "github.com/solarlune/routine"
+
+// NewEnqueue creates a Function action that appends the value returned by valueFn to the queue with the given ID (see
+// Routine.Enqueue()), and then moves on. valueFn is called each time the Action is polled, so a producer Block that
+// loops enqueues a fresh value each time around.
+func NewEnqueue(queueID any, valueFn func(block *routine.Block) any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		block.Routine().Enqueue(queueID, valueFn(block))
+		return routine.FlowNext
+	}).SetName("Enqueue " + quote(queueID))
+}
+
+// NewDequeueInto creates a Function action that idles until the queue with the given ID has a value waiting, removes
+// it from the queue (see Routine.Dequeue()), stores it in the Block's Properties under the given key, and then moves on.
+// For example, a consumer Block can show dialogue lines as they're queued:
+//
+//	r.Define("dialogue",
+//		actions.NewDequeueInto("lines", "line"),
+//		showLine, // Reads block.Properties().Get("line")
+//		actions.NewLoop(),
+//	)
+func NewDequeueInto(queueID any, propKey any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		value, ok := block.Routine().Dequeue(queueID)
+		if !ok {
+			return routine.FlowIdle
+		}
+		block.Properties().Set(propKey, value)
+		return routine.FlowNext
+	}).SetName("DequeueInto " + quote(queueID) + " " + quote(propKey))
+}
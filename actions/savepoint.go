@@ -0,0 +1,36 @@
+package actions
+
+import "github.com/solarlune/routine"
+
+type savepointKey struct{ id any }
+
+// SavepointKey returns the key of the Routine property that a Savepoint action with the given ID stores its
+// routine.Snapshot in.
+func SavepointKey(id any) any { return savepointKey{id} }
+
+// LoadSavepoint returns the routine.Snapshot stored in the given Properties by the Savepoint action with the given ID,
+// along with a boolean indicating if it was found. The Snapshot can be restored using Routine.RestoreSnapshot().
+func LoadSavepoint(props *routine.Properties, id any) (routine.Snapshot, bool) {
+	snapshot, ok := props.Get(SavepointKey(id)).(routine.Snapshot)
+	return snapshot, ok
+}
+
+// NewSavepoint creates a Function action that records a named checkpoint of the Routine's state, storing a
+// routine.Snapshot in the Routine's Properties (see SavepointKey() and LoadSavepoint()), and then moves on.
+// This allows games to implement resuming a scene from its last beat.
+func NewSavepoint(id any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		r := block.Routine()
+		r.Properties().Set(SavepointKey(id), r.Snapshot())
+		return routine.FlowNext
+	}).SetName("Savepoint " + quote(id))
+}
+
+// NewSavepointFunc creates a Function action that records a named checkpoint of the Routine's state by passing the
+// savepoint's ID and a routine.Snapshot to the given function (e.g. to write it to disk), and then moves on.
+func NewSavepointFunc(id any, onSave func(id any, snapshot routine.Snapshot)) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		onSave(id, block.Routine().Snapshot())
+		return routine.FlowNext
+	}).SetName("Savepoint " + quote(id))
+}
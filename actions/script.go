@@ -0,0 +1,87 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/solarlune/routine"
+)
+
+// Yielder records Actions for a script written as a single Go function (see NewScript()). Each method appends an
+// Action to the script; together, they read as straight-line code.
+type Yielder struct {
+	actions []routine.Action
+}
+
+// NewScript creates a Collection from a script written as a single Go function, which records Actions using the given
+// Yielder. For example:
+//
+//	r := routine.New()
+//	r.Define("intro", actions.NewScript(func(y *actions.Yielder) {
+//		y.Label("start")
+//		y.Do(func() { fmt.Println("Hello!") })
+//		y.Wait(2 * time.Second)
+//		y.JumpIf("start", func() bool { return !player.Ready })
+//	}))
+//
+// Note that the script function is called only once, when NewScript() is called, to translate it into Actions; Go
+// control flow (if statements, for loops, etc.) is therefore evaluated at that time rather than when the Block runs.
+// Use the Yielder's methods (like JumpIf()) for control flow that depends on the game's state as the Block runs.
+func NewScript(script func(y *Yielder)) *Collection {
+	y := &Yielder{}
+	script(y)
+	return NewCollection(y.actions...)
+}
+
+// Add appends the given Actions to the script.
+func (y *Yielder) Add(actions ...routine.Action) {
+	y.actions = append(y.actions, actions...)
+}
+
+// Do appends an Action that calls the given function and moves on.
+func (y *Yielder) Do(function func()) {
+	y.Add(NewFunction(func(block *routine.Block) routine.Flow {
+		function()
+		return routine.FlowNext
+	}).SetName("Do"))
+}
+
+// Wait appends an Action that waits for the given duration (see NewWait()).
+func (y *Yielder) Wait(duration time.Duration) {
+	y.Add(NewWait(duration))
+}
+
+// WaitTicks appends an Action that waits for the given number of ticks (see NewWaitTicks()).
+func (y *Yielder) WaitTicks(tickCount int) {
+	y.Add(NewWaitTicks(tickCount))
+}
+
+// WaitUntil appends an Action that waits until the given condition returns true (see NewWaitUntil()).
+func (y *Yielder) WaitUntil(condition func() bool) {
+	y.Add(NewWaitUntil(condition))
+}
+
+// Label appends a Label with the given ID, which can be jumped to (see NewLabel()).
+func (y *Yielder) Label(id any) {
+	y.Add(NewLabel(id))
+}
+
+// JumpTo appends an Action that jumps to the Label with the given ID (see NewJumpTo()).
+func (y *Yielder) JumpTo(label any) {
+	y.Add(NewJumpTo(label))
+}
+
+// JumpIf appends an Action that jumps to the Label with the given ID if the given condition returns true, and
+// otherwise moves on.
+func (y *Yielder) JumpIf(label any, condition func() bool) {
+	y.Add(NewFunction(func(block *routine.Block) routine.Flow {
+		if condition() {
+			block.JumpTo(label)
+		}
+		return routine.FlowNext
+	}).SetName("JumpIf " + quote(label)))
+}
+
+// Finish appends an Action that finishes the Block (see NewFinish()).
+func (y *Yielder) Finish() {
+	y.Add(NewFinish())
+}
@@ -0,0 +1,41 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/solarlune/routine"
+)
+
+// NewAcquireN creates a Function action that idles until n units can be acquired from the semaphore with the given ID
+// (see Routine.SetSemaphore()), acquires them, and then moves on. Together with NewReleaseN(), this limits how many
+// Blocks can be in a region of their Actions at once:
+//
+//	r.SetSemaphore("doorway", 2)
+//	r.Define("npc-1",
+//		actions.NewAcquireN("doorway", 1),
+//		walkThroughDoorway,
+//		actions.NewReleaseN("doorway", 1),
+//	)
+//
+// If n isn't positive, the semaphore isn't defined, or n is larger than its capacity, the Block fails (see Block.Fail()).
+func NewAcquireN(semID any, n int) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		acquired, err := block.Routine().TryAcquire(semID, n)
+		if err != nil {
+			return block.Fail(err)
+		}
+		if acquired {
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	}).SetName(fmt.Sprintf("AcquireN %s %d", quote(semID), n))
+}
+
+// NewReleaseN creates a Function action that releases n units back to the semaphore with the given ID (see
+// Routine.Release()), and then moves on.
+func NewReleaseN(semID any, n int) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		block.Routine().Release(semID, n)
+		return routine.FlowNext
+	}).SetName(fmt.Sprintf("ReleaseN %s %d", quote(semID), n))
+}
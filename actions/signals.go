@@ -0,0 +1,41 @@
+package actions
+
+import "github.com/solarlune/routine"
+
+// NewPulseSignal creates a Function action that emits a pulse signal with the given ID (see Routine.PulseSignal()),
+// waking up the first Block waiting for it, and then moves on.
+func NewPulseSignal(id any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		block.Routine().PulseSignal(id)
+		return routine.FlowNext
+	}).SetName("PulseSignal " + quote(id))
+}
+
+// NewLatchSignal creates a Function action that sets a latched signal with the given ID (see Routine.LatchSignal()),
+// waking up every Block waiting for it until it's cleared, and then moves on.
+func NewLatchSignal(id any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		block.Routine().LatchSignal(id)
+		return routine.FlowNext
+	}).SetName("LatchSignal " + quote(id))
+}
+
+// NewClearSignal creates a Function action that clears the signal with the given ID (see Routine.ClearSignal()), and
+// then moves on.
+func NewClearSignal(id any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		block.Routine().ClearSignal(id)
+		return routine.FlowNext
+	}).SetName("ClearSignal " + quote(id))
+}
+
+// NewWaitForSignal creates a Function action that idles until the signal with the given ID is set, consuming it if
+// it's a pulse signal (see Routine.ConsumeSignal()), and then moves on.
+func NewWaitForSignal(id any) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		if block.Routine().ConsumeSignal(id) {
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	}).SetName("WaitForSignal " + quote(id))
+}
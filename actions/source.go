@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/solarlune/routine"
+)
+
+// AnnotationSource is the annotation key (see Annotate()) under which the constructors in this package record the
+// file:line location they were called from, if source capture is enabled (see SetCaptureSource()).
+const AnnotationSource = "source"
+
+var captureSource atomic.Bool
+
+// SetCaptureSource sets whether the constructors in this package (and in the expr package) record the file:line
+// location in user code that each Action was created at, as its AnnotationSource annotation. As annotations are included
+// in the messages of panics raised by Actions and in the errors Blocks fail with, this points them to exactly where the
+// offending Action was constructed (unlike Routine.SetCaptureSource(), which records where its Block was defined).
+// This is disabled by default, as capturing the caller's location has a (small) cost; it applies to all Actions
+// created afterwards, so it should be set at startup.
+func SetCaptureSource(capture bool) {
+	captureSource.Store(capture)
+}
+
+// CaptureSource returns if the constructors in this package record the location they were called from (see
+// SetCaptureSource()).
+func CaptureSource() bool {
+	return captureSource.Load()
+}
+
+// Captured annotates the given Action with the file:line location in user code it was created at (see
+// SetCaptureSource()), if source capture is enabled, and returns it. Constructors of custom Actions can call it so that
+// their Actions are located like the built-in ones.
+func Captured[A routine.Action](action A) A {
+	if captureSource.Load() {
+		if location := callerLocation(); location != "" {
+			Annotate(action, map[string]any{AnnotationSource: location})
+		}
+	}
+	return action
+}
+
+// libraryPackages are the packages whose frames are skipped when looking for the location an Action was created at.
+var libraryPackages = []string{
+	"github.com/solarlune/routine.",
+	"github.com/solarlune/routine/actions.",
+	"github.com/solarlune/routine/expr.",
+	"github.com/solarlune/routine/graph.",
+	"github.com/solarlune/routine/quest.",
+}
+
+// callerLocation returns the file:line location of the first caller outside of this library, or an empty string if
+// there is none.
+func callerLocation() string {
+
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	for {
+		frame, more := frames.Next()
+		library := false
+		for _, pkg := range libraryPackages {
+			if strings.HasPrefix(frame.Function, pkg) {
+				library = true
+				break
+			}
+		}
+		if !library {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+
+}
@@ -0,0 +1,110 @@
+package actions
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/solarlune/routine"
+)
+
+// Interpolate returns the given template with each "{propName}" placeholder replaced by the value (formatted with
+// fmt.Sprint()) of the property with that name in the Block's local Properties or, failing that, its Routine's
+// Properties. Placeholders naming properties that don't exist are left as-is. Use "{{" and "}}" for literal braces.
+// For example, with a "playerName" property of "Mia", "Hello, {playerName}!" becomes "Hello, Mia!".
+func Interpolate(block *routine.Block, template string) string {
+
+	if !strings.ContainsAny(template, "{}") {
+		return template
+	}
+
+	var builder strings.Builder
+
+	for i := 0; i < len(template); i++ {
+
+		c := template[i]
+
+		if (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c {
+			builder.WriteByte(c)
+			i++
+			continue
+		}
+
+		if c == '{' {
+			if end := strings.IndexByte(template[i+1:], '}'); end >= 0 {
+				name := template[i+1 : i+1+end]
+				if value, ok := lookupProperty(block, name); ok {
+					builder.WriteString(fmt.Sprint(value))
+					i += end + 1
+					continue
+				}
+			}
+		}
+
+		builder.WriteByte(c)
+
+	}
+
+	return builder.String()
+
+}
+
+func lookupProperty(block *routine.Block, name string) (any, bool) {
+	if props := block.Properties(); props.Has(name) {
+		return props.Get(name), true
+	}
+	if props := block.Routine().Properties(); props.Has(name) {
+		return props.Get(name), true
+	}
+	return nil, false
+}
+
+// NewLog creates a Function action that logs the given template, interpolated against the Block's Properties (see
+// Interpolate()), using the standard log package, and then moves on.
+func NewLog(template string) *Function {
+	return NewFunction(func(block *routine.Block) routine.Flow {
+		log.Println(Interpolate(block, template))
+		return routine.FlowNext
+	}).SetName("Log " + quote(template))
+}
+
+// NewTypeText creates a Function action that reveals the given template, interpolated against the Block's Properties
+// (see Interpolate()), one character at a time at the given interval, calling apply every poll with the text revealed
+// so far (e.g. to draw a dialogue box). The template is interpolated when the action starts. If the Block is asked to
+// finish (see routine.Block.Finish()), the rest of the text is revealed immediately. Once all of the text is revealed,
+// the action moves on. The reveal follows the Block's time (see routine.Block.RunningTime()).
+func NewTypeText(template string, interval time.Duration, apply func(text string)) *Function {
+
+	var text []rune
+
+	f := newTimedFunction(func(block *routine.Block, elapsed time.Duration, frames int) routine.Flow {
+
+		if text == nil {
+			text = []rune(Interpolate(block, template))
+		}
+
+		revealed := len(text)
+		if interval > 0 && !block.StopRequested() {
+			revealed = int(elapsed / interval)
+		}
+
+		if revealed >= len(text) {
+			apply(string(text))
+			return routine.FlowNext
+		}
+
+		apply(string(text[:revealed]))
+		return routine.FlowIdle
+
+	})
+
+	start := f.InitFunc
+	f.InitFunc = func(block *routine.Block) {
+		start(block)
+		text = nil
+	}
+
+	return f.SetName("TypeText " + quote(template))
+
+}
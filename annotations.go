@@ -0,0 +1,68 @@
+package routine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ActionAnnotatable identifies an interface for an Action that carries arbitrary metadata (like an author's comment, the
+// source file and line, or a script ID; see actions.Annotate()), which tooling (like tracers, validators, and
+// exporters) uses to point back to where the Action was authored. Custom Actions can implement it by embedding
+// Annotated.
+type ActionAnnotatable interface {
+	Annotations() map[string]any        // Annotations returns the Action's metadata, or nil if it has none. The map mustn't be modified.
+	SetAnnotations(meta map[string]any) // SetAnnotations replaces the Action's metadata.
+}
+
+// Annotated stores an Action's metadata; embedding it in an Action implements ActionAnnotatable. As the metadata is
+// stored on the Action itself, it lives only as long as the Action does.
+type Annotated struct {
+	annotations map[string]any
+}
+
+// Annotations returns the metadata, or nil if there is none.
+func (a *Annotated) Annotations() map[string]any { return a.annotations }
+
+// SetAnnotations replaces the metadata.
+func (a *Annotated) SetAnnotations(meta map[string]any) { a.annotations = meta }
+
+// ActionAnnotations returns the metadata of the given Action if it's an ActionAnnotatable, or nil otherwise. The map
+// mustn't be modified.
+func ActionAnnotations(action Action) map[string]any {
+	if annotatable, ok := action.(ActionAnnotatable); ok {
+		return annotatable.Annotations()
+	}
+	return nil
+}
+
+// FormatAnnotations formats the given metadata as "{key=value, ...}", sorted by key, or returns an empty string if
+// there is none.
+func FormatAnnotations(meta map[string]any) string {
+
+	if len(meta) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, meta[key]))
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+
+}
+
+// describeAction returns the name of the given Action (see ActionName()), followed by its metadata, if any.
+func describeAction(action Action) string {
+	if meta := FormatAnnotations(ActionAnnotations(action)); meta != "" {
+		return ActionName(action) + " " + meta
+	}
+	return ActionName(action)
+}
@@ -0,0 +1,88 @@
+package routine
+
+// Bookmark represents a position in a Block relative to its nearest preceding Label (or other ActionIdentifiable),
+// rather than as a raw index, so that it survives content edits that shift the indices of Actions (see Block.Bookmark()).
+// Bookmarks are plain values, so they can be saved and restored along with game saves.
+type Bookmark struct {
+	Label    any  // The ID of the nearest Label at or preceding the position
+	HasLabel bool // Whether a Label precedes the position; if not, Offset is relative to the start of the Block
+	Offset   int  // The number of Actions between the Label (or the start of the Block) and the position
+}
+
+// Bookmark captures the Block's current position as a Bookmark under the given ID, and returns it. The position is
+// stored relative to the nearest Label at or preceding the current Action, so that jumping back to it with
+// Block.JumpToBookmark() still works after Actions have been added or removed elsewhere in the Block.
+func (b *Block) Bookmark(id any) Bookmark {
+
+	bookmark := Bookmark{Offset: b.index}
+
+	for i := b.index; i >= 0 && i < len(b.actions); i-- {
+		if label, ok := b.actions[i].(ActionIdentifiable); ok {
+			bookmark = Bookmark{Label: label.ID(), HasLabel: true, Offset: b.index - i}
+			break
+		}
+	}
+
+	b.SetBookmark(id, bookmark)
+
+	return bookmark
+
+}
+
+// SetBookmark stores the given Bookmark under the given ID (e.g. to restore bookmarks from a save file).
+func (b *Block) SetBookmark(id any, bookmark Bookmark) {
+	if b.bookmarks == nil {
+		b.bookmarks = map[any]Bookmark{}
+	}
+	b.bookmarks[id] = bookmark
+}
+
+// Bookmarks returns a copy of the Block's Bookmarks, by ID.
+func (b *Block) Bookmarks() map[any]Bookmark {
+	bookmarks := make(map[any]Bookmark, len(b.bookmarks))
+	for id, bookmark := range b.bookmarks {
+		bookmarks[id] = bookmark
+	}
+	return bookmarks
+}
+
+// BookmarkIndex returns the index of the Action the given Bookmark points to in the Block, or -1 if its Label no
+// longer exists. Offsets past the end of the Block are clamped to its last Action.
+func (b *Block) BookmarkIndex(bookmark Bookmark) int {
+
+	index := bookmark.Offset
+
+	if bookmark.HasLabel {
+		labelIndex := b.LabelIndex(bookmark.Label)
+		if labelIndex < 0 {
+			return -1
+		}
+		index += labelIndex
+	}
+
+	if index > len(b.actions)-1 {
+		index = len(b.actions) - 1
+	}
+
+	return index
+
+}
+
+// JumpToBookmark sets the Block's execution index to the position of the Bookmark stored under the given ID (see
+// Block.Bookmark()). If it finds the Bookmark (and its Label), then it will jump to and return that index. Otherwise,
+// it will return -1.
+func (b *Block) JumpToBookmark(id any) int {
+
+	bookmark, ok := b.bookmarks[id]
+	if !ok {
+		return -1
+	}
+
+	index := b.BookmarkIndex(bookmark)
+	if index >= 0 {
+		b.SetIndex(index)
+	}
+
+	return index
+
+}
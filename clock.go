@@ -0,0 +1,80 @@
+package routine
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time a Routine measures the time between Update() calls with, and that Actions consult to
+// report when they'll finish (like actions.Wait; see Routine.SetClock() and ActionWakeable). Replacing it allows Routines
+// to be driven from a game clock, a slow-motion clock, or a fake clock in tests.
+type Clock interface {
+	Now() time.Time                  // Now returns the current time.
+	Since(t time.Time) time.Duration // Since returns the time elapsed since t.
+}
+
+// SystemClock is the Clock that follows the system's wall clock, using time.Now(). It's the default Clock of Routines.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                  { return time.Now() }
+func (systemClock) Since(t time.Time) time.Duration { return time.Since(t) }
+
+// ManualClock is a Clock whose time only changes when it's advanced or set, which is useful for tests. It's safe for
+// concurrent use.
+type ManualClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewManualClock creates a new ManualClock set to the given time.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the ManualClock's current time.
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed since t, according to the ManualClock.
+func (c *ManualClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Advance moves the ManualClock's time forward by the given duration.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	c.now = c.now.Add(d)
+	c.mutex.Unlock()
+}
+
+// Set sets the ManualClock's time.
+func (c *ManualClock) Set(t time.Time) {
+	c.mutex.Lock()
+	c.now = t
+	c.mutex.Unlock()
+}
+
+// SetClock sets the Clock the Routine measures the time between Update() calls with, and that Actions consult to
+// report when they'll finish (see Clock). If the clock is nil, SystemClock is used. Note that wake times (see
+// Routine.NextWakeTime()) are expressed in the Clock's time. The Clock isn't consulted in deterministic tick mode (see
+// Routine.SetTickRate()) or delta mode (see Routine.UpdateWithDelta()).
+func (r *Routine) SetClock(clock Clock) {
+	if clock == nil {
+		clock = SystemClock
+	}
+	r.clock = clock
+	r.lastUpdate = time.Time{}
+}
+
+// Clock returns the Routine's Clock (see Routine.SetClock()).
+func (r *Routine) Clock() Clock {
+	if r.clock == nil {
+		return SystemClock
+	}
+	return r.clock
+}
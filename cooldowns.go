@@ -0,0 +1,62 @@
+package routine
+
+import "time"
+
+// Cooldowns is a registry of named cooldowns shared by all of a Routine's Blocks (see Routine.Cooldowns()), so that
+// multiple Blocks can check and consume the same cooldown (e.g. a global cooldown between NPC barks). Cooldowns follow
+// the Routine's elapsed time (see Routine.Elapsed()), so they respect its time scale and don't progress while it's
+// paused.
+type Cooldowns struct {
+	routine *Routine
+	readyAt map[any]time.Duration // The Routine's elapsed time at which each cooldown is ready again, by name.
+}
+
+// Cooldowns returns the Routine's cooldown registry.
+func (r *Routine) Cooldowns() *Cooldowns {
+	if r.cooldowns == nil {
+		r.cooldowns = &Cooldowns{routine: r, readyAt: map[any]time.Duration{}}
+	}
+	return r.cooldowns
+}
+
+// Ready returns if the cooldown with the given name is ready (i.e. it was never started, or its duration has passed).
+func (c *Cooldowns) Ready(name any) bool {
+	return c.Remaining(name) <= 0
+}
+
+// Remaining returns how much time is left before the cooldown with the given name is ready, or 0 if it's ready.
+func (c *Cooldowns) Remaining(name any) time.Duration {
+	readyAt, ok := c.readyAt[name]
+	if !ok || readyAt <= c.routine.elapsed {
+		return 0
+	}
+	return readyAt - c.routine.elapsed
+}
+
+// Start starts the cooldown with the given name, so that it isn't ready until the given duration has passed,
+// regardless of whether it was ready before.
+func (c *Cooldowns) Start(name any, duration time.Duration) {
+	c.readyAt[name] = c.routine.elapsed + duration
+}
+
+// Consume starts the cooldown with the given name for the given duration and returns true if it's ready; otherwise, it
+// returns false and leaves the cooldown alone.
+func (c *Cooldowns) Consume(name any, duration time.Duration) bool {
+	if !c.Ready(name) {
+		return false
+	}
+	c.Start(name, duration)
+	return true
+}
+
+// Reset makes the cooldown with the given name ready immediately.
+func (c *Cooldowns) Reset(name any) {
+	delete(c.readyAt, name)
+}
+
+// ResetAll makes all cooldowns ready immediately.
+func (c *Cooldowns) ResetAll() {
+	for name := range c.readyAt {
+		delete(c.readyAt, name)
+	}
+}
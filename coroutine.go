@@ -0,0 +1,181 @@
+package routine
+
+import (
+	"runtime"
+	"time"
+)
+
+// actionCancelable is implemented by Actions that hold resources (like coroutine goroutines) that must be released
+// when their Block is removed from the Routine.
+type actionCancelable interface {
+	cancel()
+}
+
+// Co is passed to the body of a coroutine Block (see Routine.DefineCoroutine()), and is used to wait between Updates.
+// Its methods must only be called from the coroutine's body.
+type Co struct {
+	block     *Block
+	resume    chan bool // Sends true to continue the body, or false to cancel it.
+	yield     chan coYield
+	running   bool // Whether the body is currently running (i.e. not parked between Updates).
+	cancelled bool
+}
+
+type coYield struct {
+	done     bool
+	panicked bool
+	panicVal any
+}
+
+// Block returns the Block running the coroutine.
+func (co *Co) Block() *Block {
+	return co.block
+}
+
+// StopRequested returns if the coroutine's Block has been asked to finish gracefully (see Block.Finish()). The Block
+// only stops once the coroutine's body returns, so long-running bodies should check this to wrap up quickly.
+func (co *Co) StopRequested() bool {
+	return co.block.StopRequested()
+}
+
+// WaitFrame parks the coroutine until the next Update in which its Block is updated.
+// If the Block is stopped, restarted, or removed in the meantime, the coroutine's goroutine exits (running any deferred
+// calls) rather than returning from WaitFrame.
+func (co *Co) WaitFrame() {
+
+	if co.cancelled {
+		runtime.Goexit()
+	}
+
+	co.yield <- coYield{}
+
+	if !<-co.resume {
+		co.cancelled = true
+		runtime.Goexit()
+	}
+
+}
+
+// WaitFrames parks the coroutine for the given number of Updates in which its Block is updated.
+func (co *Co) WaitFrames(frames int) {
+	for i := 0; i < frames; i++ {
+		co.WaitFrame()
+	}
+}
+
+// WaitDuration parks the coroutine until the given duration has passed, following the Block's time (see
+// Block.RunningTime()).
+func (co *Co) WaitDuration(duration time.Duration) {
+	start := co.block.RunningTime()
+	for co.block.RunningTime()-start < duration {
+		co.WaitFrame()
+	}
+}
+
+// WaitUntil parks the coroutine until the given condition returns true. The condition is checked immediately, and
+// then once per Update in which the Block is updated.
+func (co *Co) WaitUntil(condition func() bool) {
+	for !condition() {
+		co.WaitFrame()
+	}
+}
+
+func (co *Co) run(body func(co *Co)) {
+
+	defer func() {
+		result := coYield{done: true}
+		if r := recover(); r != nil {
+			result.panicked = true
+			result.panicVal = r
+		}
+		co.yield <- result
+	}()
+
+	if !<-co.resume {
+		co.cancelled = true
+		return
+	}
+
+	body(co)
+
+}
+
+// coroutine is the Action that drives a coroutine Block's body.
+type coroutine struct {
+	body func(co *Co)
+	co   *Co // The currently running coroutine, or nil if it hasn't started yet.
+}
+
+func (c *coroutine) Name() string { return "Coroutine" }
+
+func (c *coroutine) Init(block *Block) {
+	c.cancel()
+}
+
+func (c *coroutine) Poll(block *Block) Flow {
+
+	if c.co == nil {
+		c.co = &Co{resume: make(chan bool), yield: make(chan coYield)}
+		go c.co.run(c.body)
+	}
+
+	co := c.co
+	co.block = block
+
+	co.running = true
+	co.resume <- true
+	result := <-co.yield
+	co.running = false
+
+	if !result.done {
+		return FlowIdle
+	}
+
+	if c.co == co {
+		c.co = nil
+	}
+
+	if result.panicked {
+		panic(result.panicVal)
+	}
+
+	if co.cancelled {
+		return FlowIdle
+	}
+
+	return FlowNext
+
+}
+
+func (c *coroutine) cancel() {
+
+	co := c.co
+	if co == nil {
+		return
+	}
+	c.co = nil
+
+	// The body itself stopped or removed its Block; it exits the next time it waits.
+	if co.running {
+		co.cancelled = true
+		return
+	}
+
+	co.resume <- false
+	<-co.yield
+
+}
+
+// DefineCoroutine defines a Block whose behavior is written as a single Go function, which runs on a dedicated
+// goroutine. The goroutine only runs while the Block is being updated - it's parked between Updates whenever the body
+// waits using the given Co (e.g. co.WaitFrame() or co.WaitDuration()), so the body can safely access the same state as
+// other Actions. The Block finishes when the body returns. Stopping, restarting, or removing the Block cancels the
+// body, exiting its goroutine, and running the Block again starts the body anew. If the body panics, the panic is
+// propagated to the Routine.Update() call.
+// Like other Actions, the body mustn't call Routine.StateSnapshot(), as the Routine is locked while it runs.
+func (r *Routine) DefineCoroutine(id any, body func(co *Co)) *Block {
+	if !r.allowDefine(id) {
+		return nil
+	}
+	return r.define(id, []Action{&coroutine{body: body}})
+}
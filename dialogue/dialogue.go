@@ -0,0 +1,331 @@
+// dialogue is a package that compiles a simple, Ink-like branching dialogue script format into a Block, so that
+// narrative content can be authored in text files and run by a Routine. Lines are revealed with a typewriter effect
+// (see actions.NewTypeText()), knots become Labels, diverts become jumps to them (see actions.NewJumpTo()), and choices
+// become Gates with an option for each choice (see actions.NewGate()). How lines and choices are shown to the player
+// is up to a Presenter.
+//
+// A script looks like this:
+//
+//	// Lines before the first knot run first.
+//	Guard: Halt! Who goes there?
+//	-> gate
+//
+//	=== gate ===
+//	Guard: State your business, {playerName}.
+//	* [I'm a friend.] -> friend
+//	* {gold >= 10} [Here's something for your trouble.] -> bribe
+//	* [Never mind.] -> END
+//
+//	=== friend ===
+//	{metGuard} Guard: Back again, are we?
+//	Guard: Pass, then.
+//	-> END
+//
+//	=== bribe ===
+//	Guard: I didn't see anything.
+//
+// Each line of a script is one of:
+//
+//   - "=== name ===", which starts a knot (a section that can be diverted to) with the given name.
+//   - "-> name", which diverts to the knot with the given name; "-> END" ends the dialogue.
+//   - "* [text] -> name", which offers a choice with the given text, diverting to the given knot if it's chosen.
+//     Consecutive choices are offered together; a choice without a divert continues after them once it's chosen.
+//   - "Speaker: text" or "text", which shows a line of dialogue. Lines are interpolated against the Block's Properties
+//     when they're shown (see actions.Interpolate()), so "{name}" placeholders are replaced by the value of the "name"
+//     property.
+//   - "// comment", or an empty line, which are ignored.
+//
+// Lines, diverts and choices can be made conditional by prefixing them with a condition in braces (like
+// "{gold >= 10}"), which is evaluated against the Block's Env (see expr.BlockEnv()) when it's reached. Conditional
+// lines and diverts are skipped if the condition is false, and conditional choices aren't offered. (As a result, a line
+// without a speaker can't start with a placeholder.) Once the end of a knot is reached, the dialogue continues with the
+// next knot, unless it diverts elsewhere first.
+package dialogue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/actions"
+	"github.com/solarlune/routine/expr"
+)
+
+// End is the name of the divert target that ends the dialogue.
+const End = "END"
+
+// ErrSyntax is returned when a script can't be parsed, or diverts to a knot that doesn't exist.
+var ErrSyntax = errors.New("dialogue: syntax error")
+
+// Presenter shows dialogue to the player, and reports the player's choices. Each of its functions is optional, except
+// for Chosen, which must be set if a script offers choices.
+type Presenter struct {
+	Interval    time.Duration              // The time between each character of a line being revealed; 0 shows each line at once.
+	ShowLine    func(speaker, text string) // Called every frame with the text of the current line revealed so far. The speaker is empty if the line has none.
+	Advance     func() bool                // If set, called every frame after a line has been revealed; the dialogue continues once it returns true.
+	ShowChoices func(choices []string)     // Called when choices are offered, with the (interpolated) text of each choice.
+	Chosen      func() int                 // Called every frame while choices are offered, returning the index of the choice the player picked, or -1.
+}
+
+// Option represents a choice offered by a choice Action (see NewChoice()).
+type Option struct {
+	Text      string           // The text of the choice, interpolated against the Block's Properties (see actions.Interpolate()) when it's offered.
+	Condition *expr.Expression // If set, the choice is only offered if the Condition evaluates to true against the Block's Env.
+	Actions   []routine.Action // The Actions to run if the choice is chosen, before continuing after the choice Action.
+}
+
+// NewLine creates an Action that shows a line of dialogue through the Presenter, revealing it one character at a time
+// (see actions.NewTypeText()), and then waits until the Presenter's Advance function returns true, if it's set.
+func NewLine(p *Presenter, speaker, text string) *actions.NestedCollection {
+
+	show := actions.NewTypeText(text, p.Interval, func(text string) {
+		if p.ShowLine != nil {
+			p.ShowLine(speaker, text)
+		}
+	})
+
+	if p.Advance == nil {
+		return actions.NewNestedCollection(show)
+	}
+
+	return actions.NewNestedCollection(show, actions.NewWaitUntil(p.Advance))
+
+}
+
+// NewChoice creates an Action that offers the given Options to the player through the Presenter, waits for the player to
+// pick one of them, and then runs the Actions of the chosen Option (using a Gate with a GateOption for each Option). If
+// none of the Options are offered (because none of their conditions pass), it moves on immediately.
+// The Action keeps the player's choice in itself, so it shouldn't be run by multiple Blocks at the same time.
+func NewChoice(p *Presenter, options ...Option) *actions.NestedCollection {
+
+	offered := []int{}
+	chosen := -1
+
+	wait := actions.NewFunction(func(block *routine.Block) routine.Flow {
+		if len(offered) == 0 {
+			return routine.FlowNext
+		}
+		if p.Chosen == nil {
+			return routine.FlowIdle
+		}
+		if i := p.Chosen(); i >= 0 && i < len(offered) {
+			chosen = offered[i]
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	}).SetName(fmt.Sprintf("Choice(%d options)", len(options)))
+
+	wait.InitFunc = func(block *routine.Block) {
+
+		offered = offered[:0]
+		chosen = -1
+		texts := []string{}
+
+		for i, option := range options {
+			if option.Condition == nil || evalBool(option.Condition, block) {
+				offered = append(offered, i)
+				texts = append(texts, actions.Interpolate(block, option.Text))
+			}
+		}
+
+		if len(offered) > 0 && p.ShowChoices != nil {
+			p.ShowChoices(texts)
+		}
+
+	}
+
+	gate := actions.NewGate()
+
+	for i, option := range options {
+		i := i
+		gate.AddOption(actions.NewGateOption(func() bool { return chosen == i }, option.Actions...))
+	}
+
+	// If nothing was offered, nothing is chosen, and the Gate moves on through this empty option.
+	gate.AddOption(actions.NewGateOption(nil))
+
+	return actions.NewNestedCollection(wait, gate)
+
+}
+
+// evalBool evaluates the Expression against the Block's Env, reporting any errors to the Block's Routine.
+func evalBool(e *expr.Expression, block *routine.Block) bool {
+	result, err := e.Bool(expr.BlockEnv(block))
+	if err != nil {
+		block.Routine().ReportError(fmt.Errorf("%w (in %q, block %v)", err, e.String(), block.ID))
+		return false
+	}
+	return result
+}
+
+// Compile compiles the given script into a list of Actions, which show its dialogue through the given Presenter. An
+// error wrapping ErrSyntax is returned if the script can't be parsed, diverts to a knot that doesn't exist, or offers
+// choices without the Presenter's Chosen function being set.
+func Compile(source string, p *Presenter) ([]routine.Action, error) {
+
+	compiled := []routine.Action{}
+	knots := map[string]int{}
+	diverts := []divert{}
+
+	// The choices being gathered, and the line they started on.
+	choices := []Option{}
+	choicesLine := 0
+
+	flushChoices := func() {
+		if len(choices) > 0 {
+			compiled = append(compiled, NewChoice(p, choices...))
+			choices = nil
+		}
+	}
+
+	for i, raw := range strings.Split(source, "\n") {
+
+		lineNumber := i + 1
+		line := strings.TrimSpace(raw)
+
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "===") {
+			flushChoices()
+			name := strings.TrimSpace(strings.Trim(line, "="))
+			if name == "" || name == End || strings.ContainsAny(name, " \t") {
+				return nil, fmt.Errorf("%w: line %d: invalid knot name %q", ErrSyntax, lineNumber, name)
+			}
+			if previous, exists := knots[name]; exists {
+				return nil, fmt.Errorf("%w: line %d: knot %q is already defined on line %d", ErrSyntax, lineNumber, name, previous)
+			}
+			knots[name] = lineNumber
+			compiled = append(compiled, actions.NewLabel(name))
+			continue
+		}
+
+		if strings.HasPrefix(line, "*") {
+
+			option := Option{}
+
+			rest, condition, err := parseCondition(strings.TrimSpace(line[1:]), lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			option.Condition = condition
+
+			if !strings.HasPrefix(rest, "[") || !strings.Contains(rest, "]") {
+				return nil, fmt.Errorf("%w: line %d: choice text must be enclosed in brackets", ErrSyntax, lineNumber)
+			}
+			end := strings.Index(rest, "]")
+			option.Text = strings.TrimSpace(rest[1:end])
+			rest = strings.TrimSpace(rest[end+1:])
+
+			if rest != "" {
+				if !strings.HasPrefix(rest, "->") {
+					return nil, fmt.Errorf("%w: line %d: unexpected %q after choice", ErrSyntax, lineNumber, rest)
+				}
+				target := strings.TrimSpace(rest[2:])
+				diverts = append(diverts, divert{target: target, line: lineNumber})
+				option.Actions = []routine.Action{newDivert(target)}
+			}
+
+			if len(choices) == 0 {
+				choicesLine = lineNumber
+			}
+			choices = append(choices, option)
+			continue
+
+		}
+
+		flushChoices()
+
+		rest, condition, err := parseCondition(line, lineNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		var action routine.Action
+
+		if strings.HasPrefix(rest, "->") {
+			target := strings.TrimSpace(rest[2:])
+			diverts = append(diverts, divert{target: target, line: lineNumber})
+			action = newDivert(target)
+		} else {
+			speaker, text := "", rest
+			if colon := strings.Index(rest, ":"); colon > 0 && !strings.ContainsAny(rest[:colon], " \t{") {
+				speaker, text = rest[:colon], strings.TrimSpace(rest[colon+1:])
+			}
+			action = NewLine(p, speaker, text)
+		}
+
+		if condition != nil {
+			action = expr.NewIf(condition, action)
+		}
+
+		compiled = append(compiled, action)
+
+	}
+
+	flushChoices()
+
+	for _, d := range diverts {
+		if _, exists := knots[d.target]; !exists && d.target != End {
+			return nil, fmt.Errorf("%w: line %d: divert to unknown knot %q", ErrSyntax, d.line, d.target)
+		}
+	}
+
+	if choicesLine > 0 && p.Chosen == nil {
+		return nil, fmt.Errorf("%w: line %d: the script offers choices, but the Presenter has no Chosen function", ErrSyntax, choicesLine)
+	}
+
+	if len(compiled) == 0 {
+		return nil, fmt.Errorf("%w: the script is empty", ErrSyntax)
+	}
+
+	return compiled, nil
+
+}
+
+// Define compiles the given script (see Compile()) and defines a Block with the given ID that runs it in the Routine,
+// returning the Block. The Block isn't run automatically.
+func Define(r *routine.Routine, id any, source string, p *Presenter) (*routine.Block, error) {
+	compiled, err := Compile(source, p)
+	if err != nil {
+		return nil, err
+	}
+	return r.DefineE(id, compiled...)
+}
+
+type divert struct {
+	target string
+	line   int
+}
+
+// newDivert returns an Action that jumps to the knot with the given name, or finishes the Block if the name is End.
+func newDivert(target string) routine.Action {
+	if target == End {
+		return actions.NewFinish()
+	}
+	return actions.NewJumpTo(target)
+}
+
+// parseCondition splits a "{condition} rest" line into the rest of the line and the compiled condition, if there is one.
+func parseCondition(line string, lineNumber int) (string, *expr.Expression, error) {
+
+	if !strings.HasPrefix(line, "{") {
+		return line, nil, nil
+	}
+
+	end := strings.Index(line, "}")
+	if end < 0 {
+		return "", nil, fmt.Errorf("%w: line %d: unterminated condition", ErrSyntax, lineNumber)
+	}
+
+	condition, err := expr.Compile(line[1:end])
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: line %d: %v", ErrSyntax, lineNumber, err)
+	}
+
+	return strings.TrimSpace(line[end+1:]), condition, nil
+
+}
@@ -0,0 +1,116 @@
+package dialogue_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/dialogue"
+)
+
+const script = `
+Guard: Halt!
+-> gate
+
+=== gate ===
+Guard: State your business, {playerName}.
+* [I'm a friend.] -> friend
+* {gold >= 10} [Here's a bribe.] -> bribe
+* [Never mind.] -> END
+
+=== friend ===
+{metGuard} Guard: Back again?
+Guard: Pass, then.
+-> END
+
+=== bribe ===
+Guard: I didn't see anything.
+`
+
+// player is a Presenter that records the lines and choices shown, and picks choices from a list.
+type player struct {
+	lines   []string
+	offered [][]string
+	picks   []int
+}
+
+func (p *player) presenter() *dialogue.Presenter {
+	return &dialogue.Presenter{
+		ShowLine: func(speaker, text string) {
+			// Lines are revealed at once, so each is shown once.
+			p.lines = append(p.lines, speaker+": "+text)
+		},
+		ShowChoices: func(choices []string) {
+			p.offered = append(p.offered, choices)
+		},
+		Chosen: func() int {
+			if len(p.picks) == 0 {
+				return -1
+			}
+			pick := p.picks[0]
+			p.picks = p.picks[1:]
+			return pick
+		},
+	}
+}
+
+func run(t *testing.T, gold int, picks ...int) *player {
+	t.Helper()
+
+	r := routine.New()
+	r.Properties().Set("playerName", "Mia")
+	r.Properties().Set("gold", gold)
+
+	p := &player{picks: picks}
+	block, err := dialogue.Define(r, "dialogue", script, p.presenter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Run()
+
+	for i := 0; i < 20 && r.Running(); i++ {
+		r.Update()
+	}
+	if r.Running() {
+		t.Fatalf("dialogue didn't end")
+	}
+
+	return p
+}
+
+func TestDialogueChoices(t *testing.T) {
+
+	p := run(t, 0, 0)
+
+	expected := []string{"Guard: Halt!", "Guard: State your business, Mia.", "Guard: Pass, then."}
+	if strings.Join(p.lines, "|") != strings.Join(expected, "|") {
+		t.Fatalf("showed %q, expected %q", p.lines, expected)
+	}
+	if len(p.offered) != 1 || len(p.offered[0]) != 2 {
+		t.Fatalf("offered %q, expected the two unconditional choices", p.offered)
+	}
+
+	p = run(t, 20, 1)
+
+	if last := p.lines[len(p.lines)-1]; last != "Guard: I didn't see anything." {
+		t.Fatalf("last line was %q after bribing", last)
+	}
+	if len(p.offered) != 1 || len(p.offered[0]) != 3 {
+		t.Fatalf("offered %q, expected all three choices", p.offered)
+	}
+
+	p = run(t, 20, 2)
+
+	if len(p.lines) != 2 {
+		t.Fatalf("showed %q after choosing to end the dialogue", p.lines)
+	}
+
+}
+
+func TestDialogueUnknownKnot(t *testing.T) {
+	_, err := dialogue.Compile("Hello.\n-> nowhere", &dialogue.Presenter{})
+	if !errors.Is(err, dialogue.ErrSyntax) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected a syntax error on line 2, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package routine
+
+import (
+	"errors"
+	"log"
+)
+
+// ErrDuplicateID is reported when a Block is defined with an ID that is already in use in the Routine and the
+// Routine's DefinePolicy is set to ErrorOnDuplicate or PanicOnDuplicate.
+var ErrDuplicateID = errors.New("routine: duplicate block ID")
+
+// ErrInvalidID is returned by Routine.DefineE() when a Block ID isn't of a comparable type.
+var ErrInvalidID = errors.New("routine: invalid block ID")
+
+// ErrNoActions is returned by Routine.DefineE() when a Block is defined without any Actions. In strict mode, it is
+// also reported when an empty Block is defined or run, or when its index is set.
+var ErrNoActions = errors.New("routine: block has no actions")
+
+// ErrNilAction is returned by Routine.DefineE() when a Block is defined with a nil Action. In strict mode, it is also
+// reported when nil Actions are passed to Routine.Define() or added to a Block.
+var ErrNilAction = errors.New("routine: nil action")
+
+// ErrIndexOutOfRange is reported in strict mode when a Block's index is set outside of the range of its Actions (see
+// Block.SetIndex()). The index is clamped to the range either way.
+var ErrIndexOutOfRange = errors.New("routine: index out of range")
+
+// ErrBlockFailed is the error a Block fails with when an Action returns FlowFail without giving an error (see
+// Block.Fail()).
+var ErrBlockFailed = errors.New("routine: block failed")
+
+// ErrReentrantUpdate is reported when Routine.Update() (or one of its variants) is called while the Routine is already
+// updating, like from within an Action's Poll() function.
+var ErrReentrantUpdate = errors.New("routine: Update called while already updating")
+
+// ErrSemaphore is returned by Routine.TryAcquire() when a semaphore can't be acquired from, either because it isn't
+// defined or because more units were requested than it can hold.
+var ErrSemaphore = errors.New("routine: invalid semaphore acquisition")
+
+// ErrPreconditionTimeout is the error a Block fails with when its precondition doesn't pass within its precondition
+// timeout (see Block.SetPreconditionTimeout()).
+var ErrPreconditionTimeout = errors.New("routine: block precondition timed out")
+
+// ErrReadOnlyProperties is the error a read-only Properties view panics with (or reports, in strict mode) when a
+// mutation is attempted through it (see Properties.ReadOnly()).
+var ErrReadOnlyProperties = errors.New("routine: properties are read-only")
+
+// SetErrorHandler sets the function that is called when the Routine encounters a (non-fatal) error, like defining a
+// Block with a duplicate ID when the DefinePolicy is set to ErrorOnDuplicate. If no error handler is set (or it's set
+// to nil), errors are logged using the standard log package.
+func (r *Routine) SetErrorHandler(handler func(err error)) {
+	r.errorHandler = handler
+}
+
+// ReportError reports the given error to the Routine's error handler (see Routine.SetErrorHandler()). This can be used
+// by custom Actions to surface errors (e.g. an invalid expression) without panicking.
+func (r *Routine) ReportError(err error) {
+	if r.errorHandler != nil {
+		r.errorHandler(err)
+	} else {
+		log.Println(err)
+	}
+}
@@ -77,6 +77,12 @@ func main() {
 		// Update it.
 		myRoutine.Update()
 
+		// If every running Block is just waiting for time to pass, we can sleep until the
+		// Routine next needs to be updated rather than updating it as fast as possible.
+		if until, ok := myRoutine.CanSleepUntil(); ok && !until.IsZero() {
+			time.Sleep(time.Until(until))
+		}
+
 	}
 
 }
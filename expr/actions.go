@@ -0,0 +1,192 @@
+package expr
+
+import (
+	"fmt"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/actions"
+)
+
+// evalBool evaluates the Expression against the Block's Env, reporting any errors to the Block's Routine.
+func evalBool(e *Expression, block *routine.Block) bool {
+	result, err := e.Bool(BlockEnv(block))
+	if err != nil {
+		block.Routine().ReportError(fmt.Errorf("%w (in %q, block %v)", err, e.source, block.ID))
+		return false
+	}
+	return result
+}
+
+// eval evaluates the Expression against the Block's Env, reporting any errors to the Block's Routine.
+func eval(e *Expression, block *routine.Block) (any, bool) {
+	result, err := e.Eval(BlockEnv(block))
+	if err != nil {
+		block.Routine().ReportError(fmt.Errorf("%w (in %q, block %v)", err, e.source, block.ID))
+		return nil, false
+	}
+	return result, true
+}
+
+// NewWaitUntil creates a Function action that idles until the given Expression evaluates to true against the Block's
+// Env (see BlockEnv()). Errors that occur while evaluating the Expression are reported to the Routine's error handler.
+func NewWaitUntil(e *Expression) *actions.Function {
+	return actions.NewFunction(func(block *routine.Block) routine.Flow {
+		if evalBool(e, block) {
+			return routine.FlowNext
+		}
+		return routine.FlowIdle
+	}).SetName("WaitUntil " + e.String())
+}
+
+// NewGateOption creates an actions.GateOption that is chosen when the given Expression evaluates to true against the
+// given Env.
+func NewGateOption(e *Expression, env Env, options ...routine.Action) *actions.GateOption {
+	return actions.NewGateOption(Condition(e, env), options...)
+}
+
+// If is an Action that evaluates an Expression when it starts, and if it evaluates to true, runs the Actions it
+// contains in sequence before moving on. Otherwise, it moves on immediately.
+type If struct {
+	Expression *Expression
+	Then       *actions.NestedCollection
+	Else       *actions.NestedCollection
+	decided    bool
+	chosen     *actions.NestedCollection
+	routine.Annotated
+}
+
+// NewIf creates an If action that runs the given Actions if the Expression evaluates to true against the Block's Env
+// (see BlockEnv()). Errors that occur while evaluating the Expression are reported to the Routine's error handler, and
+// the Expression is treated as false.
+func NewIf(e *Expression, then ...routine.Action) *If {
+	return actions.Captured(&If{
+		Expression: e,
+		Then:       actions.NewNestedCollection(then...),
+	})
+}
+
+// SetElse sets the Actions to run if the Expression evaluates to false.
+func (i *If) SetElse(otherwise ...routine.Action) *If {
+	i.Else = actions.NewNestedCollection(otherwise...)
+	return i
+}
+
+func (i *If) Name() string { return "If " + i.Expression.String() }
+
+func (i *If) Init(block *routine.Block) {
+	i.decided = false
+	i.chosen = nil
+}
+
+// End ends the Actions the If chose to run, if any are still running (see routine.ActionEndable).
+func (i *If) End(block *routine.Block) {
+	if i.chosen != nil {
+		i.chosen.End(block)
+	}
+}
+
+func (i *If) Poll(block *routine.Block) routine.Flow {
+
+	if !i.decided {
+		i.decided = true
+		if evalBool(i.Expression, block) {
+			i.chosen = i.Then
+		} else {
+			i.chosen = i.Else
+		}
+		if i.chosen != nil {
+			i.chosen.Init(block)
+		}
+	}
+
+	if i.chosen == nil {
+		return routine.FlowNext
+	}
+
+	return i.chosen.Poll(block)
+
+}
+
+// NewVar creates a Function action that declares a variable in the Block's local Properties: if the Block doesn't have
+// a property with the given name yet, it's set to the result of evaluating the Expression against the Block's Env (see
+// BlockEnv()). Either way, the action then moves on. Because the variable is only set if it's missing, a looping Block
+// keeps its value across iterations (until the Block's Properties are cleared; see routine.Block.Properties()).
+// Errors that occur while evaluating the Expression are reported to the Routine's error handler, and leave the variable
+// unset.
+func NewVar(name string, e *Expression) *actions.Function {
+	return actions.NewFunction(func(block *routine.Block) routine.Flow {
+		if !block.Properties().Has(name) {
+			if value, ok := eval(e, block); ok {
+				block.Properties().Set(name, value)
+			}
+		}
+		return routine.FlowNext
+	}).SetName("Var " + name + " = " + e.String())
+}
+
+// NewLet creates a Function action that sets the property with the given name in the Block's local Properties to the
+// result of evaluating the Expression against the Block's Env (see BlockEnv()), and then moves on. For example,
+// NewLet("count", MustCompile("count + 1")) increments a counter. Errors that occur while evaluating the Expression are
+// reported to the Routine's error handler, and leave the property unchanged.
+func NewLet(name string, e *Expression) *actions.Function {
+	return actions.NewFunction(func(block *routine.Block) routine.Flow {
+		if value, ok := eval(e, block); ok {
+			block.Properties().Set(name, value)
+		}
+		return routine.FlowNext
+	}).SetName("Let " + name + " = " + e.String())
+}
+
+// While is an Action that runs the Actions it contains in sequence for as long as an Expression evaluates to true,
+// checking it before each iteration. Once it evaluates to false, the Block moves on.
+type While struct {
+	Expression *Expression
+	Body       *actions.NestedCollection
+	running    bool
+	routine.Annotated
+}
+
+// NewWhile creates a While action that runs the given Actions repeatedly while the Expression evaluates to true against
+// the Block's Env (see BlockEnv()). To keep a While whose Actions all finish immediately from looping forever, each
+// iteration after the first begins on the following Update. Errors that occur while evaluating the Expression are
+// reported to the Routine's error handler, and the Expression is treated as false.
+func NewWhile(e *Expression, body ...routine.Action) *While {
+	return actions.Captured(&While{
+		Expression: e,
+		Body:       actions.NewNestedCollection(body...),
+	})
+}
+
+func (w *While) Name() string { return "While " + w.Expression.String() }
+
+func (w *While) Init(block *routine.Block) {
+	w.running = false
+}
+
+// End ends the While's current iteration, if one is running (see routine.ActionEndable).
+func (w *While) End(block *routine.Block) {
+	if w.running {
+		w.Body.End(block)
+	}
+}
+
+func (w *While) Poll(block *routine.Block) routine.Flow {
+
+	if !w.running {
+		if !evalBool(w.Expression, block) {
+			return routine.FlowNext
+		}
+		w.running = true
+		w.Body.Init(block)
+	}
+
+	result := w.Body.Poll(block)
+
+	if result == routine.FlowNext {
+		w.running = false
+		return routine.FlowIdle
+	}
+
+	return result
+
+}
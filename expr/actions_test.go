@@ -0,0 +1,80 @@
+package expr_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/actions"
+	"github.com/solarlune/routine/expr"
+)
+
+// endable is an Action that idles forever, counting how many times it's ended.
+type endable struct {
+	ended int
+}
+
+func (e *endable) Init(block *routine.Block)              {}
+func (e *endable) Poll(block *routine.Block) routine.Flow { return routine.FlowIdle }
+func (e *endable) End(block *routine.Block)               { e.ended++ }
+
+func TestWhileWaitEachIteration(t *testing.T) {
+
+	const step = 10 * time.Millisecond
+	const wait = 100 * time.Millisecond
+
+	r := routine.New()
+	iterations := []time.Duration{}
+
+	r.Define("block",
+		expr.NewVar("i", expr.MustCompile("0")),
+		expr.NewWhile(expr.MustCompile("i < 3"),
+			actions.NewWait(wait),
+			actions.NewFunction(func(block *routine.Block) routine.Flow {
+				iterations = append(iterations, r.Elapsed())
+				return routine.FlowNext
+			}),
+			expr.NewLet("i", expr.MustCompile("i + 1")),
+		),
+	)
+	r.Run("block")
+
+	for i := 0; i < 100; i++ {
+		r.UpdateWithDelta(step)
+	}
+
+	if len(iterations) != 3 {
+		t.Fatalf("While ran %d iterations, expected 3", len(iterations))
+	}
+
+	for i := 1; i < len(iterations); i++ {
+		// Each iteration after the first begins on the following Update.
+		if d := iterations[i] - iterations[i-1]; d < wait || d > wait+step {
+			t.Errorf("iteration %d took %s, expected %s", i, d, wait)
+		}
+	}
+
+}
+
+func TestWhileIfForwardEnd(t *testing.T) {
+
+	r := routine.New()
+
+	inWhile := &endable{}
+	inIf := &endable{}
+
+	r.Define("while", expr.NewWhile(expr.MustCompile("true"), inWhile))
+	r.Define("if", expr.NewIf(expr.MustCompile("true"), inIf))
+	r.Run()
+
+	r.Update()
+	r.Stop()
+
+	if inWhile.ended != 1 {
+		t.Errorf("While's child was ended %d times, expected 1", inWhile.ended)
+	}
+	if inIf.ended != 1 {
+		t.Errorf("If's child was ended %d times, expected 1", inIf.ended)
+	}
+
+}
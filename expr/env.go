@@ -0,0 +1,43 @@
+package expr
+
+import "github.com/solarlune/routine"
+
+// Env represents an environment in which identifiers in an Expression are resolved.
+type Env interface {
+	Lookup(name string) (any, bool)
+}
+
+// EnvFunc is a function that implements Env.
+type EnvFunc func(name string) (any, bool)
+
+// Lookup resolves the identifier with the given name by calling the function.
+func (f EnvFunc) Lookup(name string) (any, bool) { return f(name) }
+
+// PropertiesEnv returns an Env that resolves identifiers using the given Properties (keyed by the identifiers' names),
+// checking each in order until the identifier is found.
+func PropertiesEnv(props ...*routine.Properties) Env {
+	return EnvFunc(func(name string) (any, bool) {
+		for _, p := range props {
+			if p != nil && p.Has(name) {
+				return p.Get(name), true
+			}
+		}
+		return nil, false
+	})
+}
+
+// BlockEnv returns an Env that resolves identifiers using the given Block's local Properties, followed by its
+// Routine's Properties.
+func BlockEnv(block *routine.Block) Env {
+	return PropertiesEnv(block.Properties(), block.Routine().Properties())
+}
+
+// Condition returns a function that evaluates the given Expression against the given Env, returning the truthiness of
+// the result. If an error occurs while evaluating it, the function returns false. This can be used as the check function
+// for a GateOption, for example.
+func Condition(e *Expression, env Env) func() bool {
+	return func() bool {
+		result, err := e.Bool(env)
+		return err == nil && result
+	}
+}
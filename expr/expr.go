@@ -0,0 +1,519 @@
+// expr is a package that provides a small expression language for conditions in data-driven scripts, like
+// "hp < 10 && hasKey". Expressions are evaluated against an Env, which resolves identifiers - usually from a Routine's
+// or Block's Properties.
+//
+// Expressions support number, string ("text" or 'text'), and boolean (true / false) literals, nil, identifiers,
+// parentheses, the arithmetic operators + - * / %, the comparison operators == != < <= > >=, and the logical operators
+// && || !. String concatenation is done with +. Numbers are evaluated as float64s. Identifiers that can't be resolved
+// evaluate to nil. When used as a condition, nil, false, 0, and "" are false; everything else is true.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrSyntax is returned when an expression can't be parsed.
+var ErrSyntax = errors.New("expr: syntax error")
+
+// ErrType is returned when an expression is evaluated with values of the wrong type (e.g. "true < 3").
+var ErrType = errors.New("expr: type error")
+
+// Expression represents a compiled expression.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile compiles the given source into an Expression, returning an error (wrapping ErrSyntax) if it can't be parsed.
+func Compile(source string) (*Expression, error) {
+
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, t.text, t.pos)
+	}
+
+	return &Expression{source: source, root: root}, nil
+
+}
+
+// MustCompile is like Compile, but panics if the source can't be parsed.
+func MustCompile(source string) *Expression {
+	e, err := Compile(source)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the source of the Expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the Expression using the given Env, returning the resulting value (a float64, string, bool, or nil,
+// unless an identifier resolves to a value of another type).
+func (e *Expression) Eval(env Env) (any, error) {
+	return e.root.eval(env)
+}
+
+// Bool evaluates the Expression using the given Env, returning the truthiness of the result.
+func (e *Expression) Bool(env Env) (bool, error) {
+	value, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return Truthy(value), nil
+}
+
+// Truthy returns if the given value is considered true when used as a condition. nil, false, 0, and "" are false;
+// everything else is true.
+func Truthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if n, ok := toNumber(value); ok {
+		return n != 0
+	}
+	return true
+}
+
+// toNumber converts numeric values to float64s.
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// normalize converts numeric values to float64s, so that they can be compared with number literals.
+func normalize(value any) any {
+	if n, ok := toNumber(value); ok {
+		return n
+	}
+	return value
+}
+
+type tokenKind uint8
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")"}
+
+func lex(source string) ([]token, error) {
+
+	tokens := []token{}
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+
+		r := runes[i]
+
+		switch {
+
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var text strings.Builder
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrSyntax, start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), pos: start})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrSyntax, r, i)
+			}
+
+		}
+
+	}
+
+	tokens = append(tokens, token{kind: tokenEnd, pos: len(runes)})
+
+	return tokens, nil
+
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseBinary(parseOperand func() (node, error), ops ...string) (node, error) {
+
+	left, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.acceptOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+func (p *parser) parseComparison() (node, error) {
+	return p.parseBinary(p.parseAdditive, "==", "!=", "<=", ">=", "<", ">")
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+func (p *parser) parseUnary() (node, error) {
+
+	if op, ok := p.acceptOp("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+
+}
+
+func (p *parser) parsePrimary() (node, error) {
+
+	t := p.next()
+
+	switch t.kind {
+
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q at position %d", ErrSyntax, t.text, t.pos)
+		}
+		return &literalNode{value: n}, nil
+
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "nil":
+			return &literalNode{value: nil}, nil
+		}
+		return &identNode{name: t.text}, nil
+
+	case tokenOp:
+		if t.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.acceptOp(")"); !ok {
+				return nil, fmt.Errorf("%w: expected ) at position %d", ErrSyntax, p.peek().pos)
+			}
+			return inner, nil
+		}
+
+	case tokenEnd:
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrSyntax)
+
+	}
+
+	return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, t.text, t.pos)
+
+}
+
+type node interface {
+	eval(env Env) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(env Env) (any, error) { return n.value, nil }
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(env Env) (any, error) {
+	if env == nil {
+		return nil, nil
+	}
+	value, _ := env.Lookup(n.name)
+	return normalize(value), nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(env Env) (any, error) {
+
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "!" {
+		return !Truthy(value), nil
+	}
+
+	number, ok := toNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("%w: can't negate %T", ErrType, value)
+	}
+	return -number, nil
+
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(env Env) (any, error) {
+
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || short-circuit.
+	switch n.op {
+	case "&&":
+		if !Truthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return Truthy(right), nil
+	case "||":
+		if Truthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return Truthy(right), nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	if n.op == "+" {
+		if ls, ok := left.(string); ok {
+			return ls + fmt.Sprint(right), nil
+		}
+		if rs, ok := right.(string); ok {
+			return fmt.Sprint(left) + rs, nil
+		}
+	}
+
+	if ls, ok := left.(string); ok {
+		if rs, ok := right.(string); ok {
+			switch n.op {
+			case "<":
+				return ls < rs, nil
+			case "<=":
+				return ls <= rs, nil
+			case ">":
+				return ls > rs, nil
+			case ">=":
+				return ls >= rs, nil
+			}
+		}
+	}
+
+	ln, lok := toNumber(left)
+	rn, rok := toNumber(right)
+
+	if !lok || !rok {
+		return nil, fmt.Errorf("%w: can't apply %s to %T and %T", ErrType, n.op, left, right)
+	}
+
+	switch n.op {
+	case "<":
+		return ln < rn, nil
+	case "<=":
+		return ln <= rn, nil
+	case ">":
+		return ln > rn, nil
+	case ">=":
+		return ln >= rn, nil
+	case "+":
+		return ln + rn, nil
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	case "/":
+		return ln / rn, nil
+	case "%":
+		if int64(rn) == 0 {
+			return nil, fmt.Errorf("%w: modulo by zero", ErrType)
+		}
+		return float64(int64(ln) % int64(rn)), nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown operator %s", ErrSyntax, n.op)
+
+}
+
+func equal(left, right any) (result bool) {
+	defer func() {
+		// Comparing uncomparable values (e.g. slices) panics; they're simply not equal.
+		if recover() != nil {
+			result = false
+		}
+	}()
+	return normalize(left) == normalize(right)
+}
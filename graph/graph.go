@@ -0,0 +1,346 @@
+// graph is a package that defines a stable JSON interchange format for Routines (their Blocks, Actions, and the
+// Actions' parameters), intended for use with external node-graph editors. It provides functions to export a Routine to
+// JSON, and to validate and import JSON documents into a Routine using a Registry of Action constructors.
+//
+// A document looks like this:
+//
+//	{
+//		"version": 1,
+//		"blocks": [
+//			{
+//				"id": "intro",
+//				"actions": [
+//					{ "type": "label", "params": { "id": "start" } },
+//					{ "type": "wait", "params": { "duration": "2s" } },
+//					{ "type": "jump", "params": { "label": "start" } }
+//				]
+//			}
+//		]
+//	}
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/solarlune/routine"
+)
+
+// Version is the version of the document format written by Export().
+const Version = 1
+
+// Annotation keys used to remember the type and parameters of imported Actions, so that they can be exported again.
+const (
+	AnnotationType   = "graph.type"
+	AnnotationParams = "graph.params"
+)
+
+// Document represents a JSON document describing a Routine.
+type Document struct {
+	Version int        `json:"version"`
+	Blocks  []BlockDef `json:"blocks"`
+}
+
+// BlockDef represents a Block in a Document.
+type BlockDef struct {
+	ID      string      `json:"id"`
+	Actions []ActionDef `json:"actions"`
+}
+
+// ActionDef represents an Action in a Document. Type identifies the constructor used to create the Action from a
+// Registry, and Params are passed to it. Annotations hold arbitrary metadata (e.g. an author's comment or the location
+// the Action was authored at in an editor), which is attached to the Action when it's imported (see actions.Annotate()).
+type ActionDef struct {
+	Type        string         `json:"type"`
+	Params      Params         `json:"params,omitempty"`
+	Annotations map[string]any `json:"annotations,omitempty"`
+}
+
+// Problem represents a single problem found when validating a Document, along with its location (e.g. "blocks[2].actions[3]")
+// and the annotations of the Action it was found in, if any.
+type Problem struct {
+	Path        string
+	Message     string
+	Annotations map[string]any
+}
+
+func (p Problem) String() string {
+	message := p.Message
+	if meta := routine.FormatAnnotations(p.Annotations); meta != "" {
+		message += " " + meta
+	}
+	if p.Path == "" {
+		return message
+	}
+	return p.Path + ": " + message
+}
+
+// ErrInvalid is wrapped by ValidationErrors.
+var ErrInvalid = errors.New("graph: invalid document")
+
+// ValidationError is returned when a Document is invalid, listing all of the problems found.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (v *ValidationError) Error() string {
+	lines := make([]string, 0, len(v.Problems))
+	for _, p := range v.Problems {
+		lines = append(lines, p.String())
+	}
+	return ErrInvalid.Error() + ":\n" + strings.Join(lines, "\n")
+}
+
+func (v *ValidationError) Unwrap() error { return ErrInvalid }
+
+// Decode parses the given JSON data into a Document. Syntax errors are reported with their line and column.
+func Decode(data []byte) (*Document, error) {
+
+	doc := &Document{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(doc); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) {
+			line, col := position(data, syntaxErr.Offset)
+			return nil, &ValidationError{Problems: []Problem{{Path: fmt.Sprintf("line %d, column %d", line, col), Message: syntaxErr.Error()}}}
+		} else if errors.As(err, &typeErr) {
+			line, col := position(data, typeErr.Offset)
+			return nil, &ValidationError{Problems: []Problem{{Path: fmt.Sprintf("line %d, column %d (%s)", line, col, typeErr.Field), Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}}}
+		}
+		return nil, &ValidationError{Problems: []Problem{{Message: err.Error()}}}
+	}
+
+	return doc, nil
+
+}
+
+func position(data []byte, offset int64) (line, col int) {
+	line, col = 1, 1
+	for i := int64(0); i < offset && i < int64(len(data)); i++ {
+		if data[i] == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// Validate checks the Document against the given Registry, returning a ValidationError listing every problem found
+// (unsupported versions, missing or duplicate Block IDs, unknown Action types, invalid parameters, and jumps to Labels
+// that don't exist in their Block, including jumps nested in other Actions), or nil if the Document is valid.
+func (d *Document) Validate(reg *Registry) error {
+	_, problems := d.build(reg)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Build validates the Document against the given Registry and, if it's valid, defines its Blocks in the given Routine.
+// If the Document is invalid, a ValidationError is returned and the Routine is left untouched.
+func (d *Document) Build(r *routine.Routine, reg *Registry) error {
+
+	blocks, problems := d.build(reg)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	// Check that every Block can be defined before defining any of them, so that the Routine is left untouched.
+	for i, def := range d.Blocks {
+		blockPath := fmt.Sprintf("blocks[%d]", i)
+		flattened := routine.FlattenActions(blocks[i]...)
+		if len(flattened) == 0 {
+			problems = append(problems, Problem{Path: blockPath, Message: "block has no actions once flattened"})
+		}
+		for ai, action := range flattened {
+			if action == nil {
+				problems = append(problems, Problem{Path: blockPath, Message: fmt.Sprintf("flattened action %d is nil", ai)})
+			}
+		}
+		if r.DefinePolicy() != routine.ReplaceExisting && r.BlockByID(def.ID) != nil {
+			problems = append(problems, Problem{Path: blockPath, Message: fmt.Sprintf("block ID %q is already defined in the routine", def.ID)})
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	for i, def := range d.Blocks {
+		if _, err := r.DefineE(def.ID, blocks[i]...); err != nil {
+			return fmt.Errorf("blocks[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+
+}
+
+func (d *Document) build(reg *Registry) ([][]routine.Action, []Problem) {
+
+	problems := []Problem{}
+
+	if d.Version != Version {
+		problems = append(problems, Problem{Path: "version", Message: fmt.Sprintf("unsupported version %d (expected %d)", d.Version, Version)})
+	}
+
+	blockIDs := map[string]int{}
+	blocks := make([][]routine.Action, len(d.Blocks))
+
+	for bi, block := range d.Blocks {
+
+		blockPath := fmt.Sprintf("blocks[%d]", bi)
+
+		if block.ID == "" {
+			problems = append(problems, Problem{Path: blockPath, Message: "missing block ID"})
+		} else if prev, exists := blockIDs[block.ID]; exists {
+			problems = append(problems, Problem{Path: blockPath, Message: fmt.Sprintf("duplicate block ID %q (also used by blocks[%d])", block.ID, prev)})
+		} else {
+			blockIDs[block.ID] = bi
+		}
+
+		if len(block.Actions) == 0 {
+			problems = append(problems, Problem{Path: blockPath, Message: "block has no actions"})
+		}
+
+		labels := map[any]bool{}
+		for _, action := range block.Actions {
+			if action.Type == "label" {
+				labels[action.Params["id"]] = true
+			}
+		}
+
+		problems = append(problems, checkJumps(block.Actions, blockPath+".actions", labels)...)
+
+		for ai, def := range block.Actions {
+
+			actionPath := fmt.Sprintf("%s.actions[%d] (%s)", blockPath, ai, def.Type)
+
+			action, err := reg.Construct(def)
+			if err != nil {
+				problems = append(problems, Problem{Path: actionPath, Message: err.Error(), Annotations: def.Annotations})
+				continue
+			}
+
+			blocks[bi] = append(blocks[bi], action)
+
+		}
+
+	}
+
+	return blocks, problems
+
+}
+
+// checkJumps returns a Problem for each jump in the given list of ActionDefs (found at the given path) to a Label that
+// isn't in the given set. Jumps nested in the Actions' parameters (like the bodies of "if" and "while" actions) are
+// checked as well; as they jump within the Block, they're checked against its top-level Labels.
+func checkJumps(defs []ActionDef, path string, labels map[any]bool) []Problem {
+
+	problems := []Problem{}
+
+	for i, def := range defs {
+
+		actionPath := fmt.Sprintf("%s[%d] (%s)", path, i, def.Type)
+
+		if def.Type == "jump" && !labels[def.Params["label"]] {
+			problems = append(problems, Problem{Path: actionPath, Message: fmt.Sprintf("jump to missing label %v", def.Params["label"]), Annotations: def.Annotations})
+		}
+
+		keys := make([]string, 0, len(def.Params))
+		for key := range def.Params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if nested, ok := nestedActionDefs(def.Params[key]); ok {
+				problems = append(problems, checkJumps(nested, actionPath+"."+key, labels)...)
+			}
+		}
+
+	}
+
+	return problems
+
+}
+
+// nestedActionDefs returns the ActionDefs described by the given parameter value, if it's a list of action objects (as
+// read by Registry.ConstructList()).
+func nestedActionDefs(value any) ([]ActionDef, bool) {
+
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return nil, false
+	}
+
+	defs := make([]ActionDef, 0, len(list))
+
+	for _, item := range list {
+		object, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		def := ActionDef{}
+		if def.Type, ok = object["type"].(string); !ok {
+			return nil, false
+		}
+		def.Params, _ = object["params"].(map[string]any)
+		def.Annotations, _ = object["annotations"].(map[string]any)
+		defs = append(defs, def)
+	}
+
+	return defs, true
+
+}
+
+// Import decodes the given JSON data, validates it, and defines its Blocks in the given Routine using the given Registry.
+func Import(data []byte, r *routine.Routine, reg *Registry) error {
+	doc, err := Decode(data)
+	if err != nil {
+		return err
+	}
+	return doc.Build(r, reg)
+}
+
+// Export exports the given Routine to a Document, using the given Registry to describe its Actions. Actions that were
+// imported (or annotated with AnnotationType and AnnotationParams) are described using their annotations; otherwise, the
+// Registry's exporters are tried in turn. Actions that can't be described are exported with an "opaque" type and
+// "go_type" and "name" parameters (see routine.ActionName()), so that editors can still display them. Actions'
+// annotations (see actions.Annotate()) are exported as well. Block IDs are exported as strings, using fmt.Sprint().
+func Export(r *routine.Routine, reg *Registry) *Document {
+
+	doc := &Document{Version: Version, Blocks: []BlockDef{}}
+
+	r.ForEachBlock(func(block *routine.Block) bool {
+
+		def := BlockDef{ID: fmt.Sprint(block.ID), Actions: []ActionDef{}}
+
+		block.ForEachAction(func(i int, action routine.Action) bool {
+			def.Actions = append(def.Actions, reg.describe(action))
+			return true
+		})
+
+		doc.Blocks = append(doc.Blocks, def)
+		return true
+
+	})
+
+	return doc
+
+}
+
+// ExportJSON exports the given Routine to indented JSON (see Export()).
+func ExportJSON(r *routine.Routine, reg *Registry) ([]byte, error) {
+	return json.MarshalIndent(Export(r, reg), "", "\t")
+}
@@ -0,0 +1,79 @@
+package graph_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/graph"
+)
+
+const document = `{
+	"version": 1,
+	"blocks": [
+		{
+			"id": "intro",
+			"actions": [
+				{ "type": "label", "params": { "id": "start" } },
+				{ "type": "wait", "params": { "duration": "2s" }, "annotations": { "comment": "pause" } },
+				{ "type": "if", "params": { "condition": "count > 3", "then": [ { "type": "jump", "params": { "label": "start" } } ] } },
+				{ "type": "finish" }
+			]
+		}
+	]
+}`
+
+func TestRoundTrip(t *testing.T) {
+
+	reg := graph.NewRegistry()
+
+	r := routine.New()
+	if err := graph.Import([]byte(document), r, reg); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := graph.ExportJSON(r, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reimported := routine.New()
+	if err := graph.Import(exported, reimported, reg); err != nil {
+		t.Fatalf("exported document couldn't be imported again: %v\n%s", err, exported)
+	}
+
+	again, err := graph.ExportJSON(reimported, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(exported, again) {
+		t.Fatalf("document changed after a round trip:\n%s\n\nvs.\n\n%s", exported, again)
+	}
+
+	for _, expected := range []string{`"comment": "pause"`, `"condition": "count \u003e 3"`, `"type": "jump"`} {
+		if !strings.Contains(string(exported), expected) {
+			t.Fatalf("exported document doesn't contain %s:\n%s", expected, exported)
+		}
+	}
+
+}
+
+func TestNestedJumpToMissingLabel(t *testing.T) {
+
+	doc := strings.Replace(document, `"label": "start"`, `"label": "missing"`, 1)
+
+	r := routine.New()
+	err := graph.Import([]byte(doc), r, graph.NewRegistry())
+
+	if !errors.Is(err, graph.ErrInvalid) || !strings.Contains(err.Error(), "actions[2] (if).then[0] (jump): jump to missing label missing") {
+		t.Fatalf("expected the nested jump to be rejected, got %v", err)
+	}
+
+	if len(r.BlockList()) != 0 {
+		t.Fatalf("Blocks were defined from an invalid document")
+	}
+
+}
@@ -0,0 +1,518 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/actions"
+	"github.com/solarlune/routine/expr"
+)
+
+// Params represents the parameters of an Action in a Document, as decoded from JSON (so numbers are float64s).
+type Params map[string]any
+
+// Has returns if the Params has a value for the given key.
+func (p Params) Has(key string) bool {
+	_, ok := p[key]
+	return ok
+}
+
+// String returns the string parameter under the given key, or an error if it's missing or not a string.
+func (p Params) String(key string) (string, error) {
+	value, ok := p[key]
+	if !ok {
+		return "", fmt.Errorf("missing parameter %q", key)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("parameter %q must be a string, got %T", key, value)
+	}
+	return s, nil
+}
+
+// Float returns the number parameter under the given key, or an error if it's missing or not a number.
+func (p Params) Float(key string) (float64, error) {
+	value, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("missing parameter %q", key)
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	}
+	return 0, fmt.Errorf("parameter %q must be a number, got %T", key, value)
+}
+
+// Int returns the integer parameter under the given key, or an error if it's missing or not a whole number.
+func (p Params) Int(key string) (int, error) {
+	f, err := p.Float(key)
+	if err != nil {
+		return 0, err
+	}
+	if f != float64(int(f)) {
+		return 0, fmt.Errorf("parameter %q must be a whole number, got %v", key, f)
+	}
+	return int(f), nil
+}
+
+// Duration returns the duration parameter under the given key, or an error if it's missing or invalid. Durations can
+// be given either as strings parseable by time.ParseDuration() (e.g. "1.5s"), or as numbers of seconds.
+func (p Params) Duration(key string) (time.Duration, error) {
+	value, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("missing parameter %q", key)
+	}
+	switch v := value.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %q: %w", key, err)
+		}
+		return d, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("parameter %q must be a duration string or a number of seconds, got %T", key, value)
+}
+
+// Value returns the parameter under the given key, whatever its type, or an error if it's missing.
+func (p Params) Value(key string) (any, error) {
+	value, ok := p[key]
+	if !ok {
+		return nil, fmt.Errorf("missing parameter %q", key)
+	}
+	return value, nil
+}
+
+// List returns the list of values under the given key, converted to a slice of anys, or an error if it's missing
+// or not a list. A single value is treated as a list of one.
+func (p Params) List(key string) ([]any, error) {
+	value, ok := p[key]
+	if !ok {
+		return nil, fmt.Errorf("missing parameter %q", key)
+	}
+	if list, ok := value.([]any); ok {
+		return list, nil
+	}
+	return []any{value}, nil
+}
+
+// Expression compiles the expression source string under the given key (see the expr package), or returns an error
+// if it's missing, not a string, or invalid. Numbers and booleans are also accepted, as constant expressions.
+func (p Params) Expression(key string) (*expr.Expression, error) {
+	value, ok := p[key]
+	if !ok {
+		return nil, fmt.Errorf("missing parameter %q", key)
+	}
+	var source string
+	switch v := value.(type) {
+	case string:
+		source = v
+	case float64, bool:
+		source = fmt.Sprint(v)
+	default:
+		return nil, fmt.Errorf("parameter %q must be an expression string, got %T", key, value)
+	}
+	e, err := expr.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("parameter %q: %w", key, err)
+	}
+	return e, nil
+}
+
+// Constructor creates an Action from the given Params, returning an error describing any invalid parameters.
+type Constructor func(params Params) (routine.Action, error)
+
+// Exporter describes the given Action as an ActionDef, returning false if it doesn't know how to describe it.
+type Exporter func(action routine.Action) (ActionDef, bool)
+
+// Registry maps Action type names in Documents to Constructors used to create them, and holds Exporters used to
+// describe Actions when exporting.
+type Registry struct {
+	constructors map[string]Constructor
+	exporters    []Exporter
+}
+
+// NewRegistry creates a new Registry with the built-in Action types registered:
+//
+//	wait          { "duration": "1s" }
+//	wait-ticks    { "ticks": 10 }
+//	label         { "id": "start" }
+//	jump          { "label": "start" }
+//	run-block     { "blocks": ["a", "b"] }
+//	pause-block   { "blocks": ["a", "b"] }
+//	stop-block    { "blocks": ["a", "b"] }
+//	restart-block { "blocks": ["a", "b"] }
+//	switch-block  { "blocks": ["a", "b"] }
+//	set-index     { "index": 0 }
+//	set-flag      { "key": "door-open" }
+//	clear-flag    { "key": "door-open" }
+//	wait-for-flag { "key": "door-open" }
+//	count-up-to   { "key": "visits", "n": 3 }
+//	curve         { "key": "alpha", "duration": "1s", "points": [{ "time": 0, "value": 0, "easing": "in" }, { "time": 1, "value": 1 }] }
+//	finish        {}
+//	restart       {}
+//	loop          {}
+//	log           { "text": "Hello, {playerName}!" }
+//	var           { "name": "count", "value": "0" }
+//	let           { "name": "count", "value": "count + 1" }
+//	if            { "condition": "count > 3", "then": [ ...actions ], "else": [ ...actions ] }
+//	while         { "condition": "count < 3", "do": [ ...actions ] }
+//
+// The variable actions operate on the Block's local Properties, and their values and conditions are expressions
+// (see the expr package).
+func NewRegistry() *Registry {
+
+	reg := &Registry{
+		constructors: map[string]Constructor{},
+	}
+
+	reg.Register("wait", func(params Params) (routine.Action, error) {
+		d, err := params.Duration("duration")
+		if err != nil {
+			return nil, err
+		}
+		return actions.NewWait(d), nil
+	})
+
+	reg.Register("wait-ticks", func(params Params) (routine.Action, error) {
+		ticks, err := params.Int("ticks")
+		if err != nil {
+			return nil, err
+		}
+		return actions.NewWaitTicks(ticks), nil
+	})
+
+	reg.Register("label", func(params Params) (routine.Action, error) {
+		id, err := params.Value("id")
+		if err != nil {
+			return nil, err
+		}
+		return actions.NewLabel(id), nil
+	})
+
+	reg.Register("jump", func(params Params) (routine.Action, error) {
+		label, err := params.Value("label")
+		if err != nil {
+			return nil, err
+		}
+		return actions.NewJumpTo(label), nil
+	})
+
+	blockActions := map[string]func(blockIDs ...any) *actions.Function{
+		"run-block":     actions.NewRunBlock,
+		"pause-block":   actions.NewPauseBlock,
+		"stop-block":    actions.NewStopBlock,
+		"restart-block": actions.NewRestartBlock,
+		"switch-block":  actions.NewSwitchBlock,
+	}
+
+	for name, constructor := range blockActions {
+		constructor := constructor
+		reg.Register(name, func(params Params) (routine.Action, error) {
+			blocks, err := params.List("blocks")
+			if err != nil {
+				return nil, err
+			}
+			return constructor(blocks...), nil
+		})
+	}
+
+	reg.Register("set-index", func(params Params) (routine.Action, error) {
+		index, err := params.Int("index")
+		if err != nil {
+			return nil, err
+		}
+		return actions.NewSetIndex(index), nil
+	})
+
+	flagActions := map[string]func(key any) *actions.Function{
+		"set-flag":      actions.NewSetFlag,
+		"clear-flag":    actions.NewClearFlag,
+		"wait-for-flag": actions.NewWaitForFlag,
+	}
+
+	for name, constructor := range flagActions {
+		constructor := constructor
+		reg.Register(name, func(params Params) (routine.Action, error) {
+			key, err := params.Value("key")
+			if err != nil {
+				return nil, err
+			}
+			return constructor(key), nil
+		})
+	}
+
+	reg.Register("count-up-to", func(params Params) (routine.Action, error) {
+		key, err := params.Value("key")
+		if err != nil {
+			return nil, err
+		}
+		n, err := params.Int("n")
+		if err != nil {
+			return nil, err
+		}
+		return actions.NewCountUpTo(key, n), nil
+	})
+
+	reg.Register("curve", func(params Params) (routine.Action, error) {
+		key, err := params.Value("key")
+		if err != nil {
+			return nil, err
+		}
+		d, err := params.Duration("duration")
+		if err != nil {
+			return nil, err
+		}
+		list, err := params.List("points")
+		if err != nil {
+			return nil, err
+		}
+		points := make([]actions.CurvePoint, 0, len(list))
+		for i, value := range list {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("point %d must be an object, got %T", i, value)
+			}
+			fields := Params(object)
+			point := actions.CurvePoint{}
+			if point.Time, err = fields.Float("time"); err != nil {
+				return nil, fmt.Errorf("point %d: %w", i, err)
+			}
+			if point.Value, err = fields.Float("value"); err != nil {
+				return nil, fmt.Errorf("point %d: %w", i, err)
+			}
+			if fields.Has("easing") {
+				easing, err := fields.String("easing")
+				if err != nil {
+					return nil, fmt.Errorf("point %d: %w", i, err)
+				}
+				point.Easing = actions.Easing(easing)
+			}
+			points = append(points, point)
+		}
+		return actions.NewCurve(key, points, d), nil
+	})
+
+	reg.Register("finish", func(params Params) (routine.Action, error) { return actions.NewFinish(), nil })
+	reg.Register("restart", func(params Params) (routine.Action, error) { return actions.NewRestart(), nil })
+	reg.Register("loop", func(params Params) (routine.Action, error) { return actions.NewLoop(), nil })
+
+	reg.Register("log", func(params Params) (routine.Action, error) {
+		text, err := params.String("text")
+		if err != nil {
+			return nil, err
+		}
+		return actions.NewLog(text), nil
+	})
+
+	variableActions := map[string]func(name string, e *expr.Expression) *actions.Function{
+		"var": expr.NewVar,
+		"let": expr.NewLet,
+	}
+
+	for name, constructor := range variableActions {
+		constructor := constructor
+		reg.Register(name, func(params Params) (routine.Action, error) {
+			varName, err := params.String("name")
+			if err != nil {
+				return nil, err
+			}
+			e, err := params.Expression("value")
+			if err != nil {
+				return nil, err
+			}
+			return constructor(varName, e), nil
+		})
+	}
+
+	reg.Register("if", func(params Params) (routine.Action, error) {
+		e, err := params.Expression("condition")
+		if err != nil {
+			return nil, err
+		}
+		then, err := reg.ConstructList(params, "then")
+		if err != nil {
+			return nil, err
+		}
+		action := expr.NewIf(e, then...)
+		if params.Has("else") {
+			otherwise, err := reg.ConstructList(params, "else")
+			if err != nil {
+				return nil, err
+			}
+			action.SetElse(otherwise...)
+		}
+		return action, nil
+	})
+
+	reg.Register("while", func(params Params) (routine.Action, error) {
+		e, err := params.Expression("condition")
+		if err != nil {
+			return nil, err
+		}
+		body, err := reg.ConstructList(params, "do")
+		if err != nil {
+			return nil, err
+		}
+		return expr.NewWhile(e, body...), nil
+	})
+
+	// Actions created outside of Import() can only be described if they carry their own parameters.
+	reg.AddExporter(func(action routine.Action) (ActionDef, bool) {
+		switch a := action.(type) {
+		case *actions.Wait:
+			return ActionDef{Type: "wait", Params: Params{"duration": a.Duration.String()}}, true
+		case *actions.Label:
+			return ActionDef{Type: "label", Params: Params{"id": a.Label}}, true
+		}
+		return ActionDef{}, false
+	})
+
+	return reg
+
+}
+
+// Register registers the given Constructor under the given Action type name, replacing any existing Constructor
+// registered under that name.
+func (r *Registry) Register(typeName string, constructor Constructor) {
+	r.constructors[typeName] = constructor
+}
+
+// Construct creates the Action described by the given ActionDef using the Constructor registered under its type name,
+// annotating it with the ActionDef's Annotations, as well as its type and parameters so that it can be exported again.
+func (r *Registry) Construct(def ActionDef) (routine.Action, error) {
+
+	constructor, ok := r.constructors[def.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown action type %q", def.Type)
+	}
+
+	action, err := constructor(def.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	if action == nil {
+		return nil, errors.New("constructor returned a nil action")
+	}
+
+	meta := map[string]any{}
+	for k, v := range def.Annotations {
+		meta[k] = v
+	}
+	meta[AnnotationType] = def.Type
+	meta[AnnotationParams] = def.Params
+
+	actions.Annotate(action, meta)
+
+	return action, nil
+
+}
+
+// ConstructList creates the Actions described by the list of action objects (each with a "type" and optional
+// "params", as in a BlockDef) under the given key in the Params. This allows Constructors to create Actions that
+// contain other Actions (like "if" and "while"). A missing key is treated as an empty list.
+func (r *Registry) ConstructList(params Params, key string) ([]routine.Action, error) {
+
+	if !params.Has(key) {
+		return nil, nil
+	}
+
+	list, err := params.List(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]routine.Action, 0, len(list))
+
+	for i, value := range list {
+
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be an action object, got %T", key, i, value)
+		}
+
+		def := ActionDef{}
+		if def.Type, ok = object["type"].(string); !ok {
+			return nil, fmt.Errorf("%s[%d] is missing its action type", key, i)
+		}
+		if object["params"] != nil {
+			fields, ok := object["params"].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s[%d] params must be an object, got %T", key, i, object["params"])
+			}
+			def.Params = Params(fields)
+		}
+		if object["annotations"] != nil {
+			if def.Annotations, ok = object["annotations"].(map[string]any); !ok {
+				return nil, fmt.Errorf("%s[%d] annotations must be an object, got %T", key, i, object["annotations"])
+			}
+		}
+
+		action, err := r.Construct(def)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d] (%s): %w", key, i, def.Type, err)
+		}
+
+		result = append(result, action)
+
+	}
+
+	return result, nil
+
+}
+
+// Types returns the Action type names registered in the Registry, sorted alphabetically.
+func (r *Registry) Types() []string {
+	names := make([]string, 0, len(r.constructors))
+	for name := range r.constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddExporter adds an Exporter to the Registry, used to describe Actions that weren't created through Import() when
+// exporting. Exporters are tried in the order they were added.
+func (r *Registry) AddExporter(exporter Exporter) {
+	r.exporters = append(r.exporters, exporter)
+}
+
+func (r *Registry) describe(action routine.Action) ActionDef {
+	def := r.describeType(action)
+	for k, v := range routine.ActionAnnotations(action) {
+		if k == AnnotationType || k == AnnotationParams {
+			continue
+		}
+		if def.Annotations == nil {
+			def.Annotations = map[string]any{}
+		}
+		def.Annotations[k] = v
+	}
+	return def
+}
+
+func (r *Registry) describeType(action routine.Action) ActionDef {
+
+	if meta := routine.ActionAnnotations(action); meta != nil {
+		if typeName, ok := meta[AnnotationType].(string); ok {
+			params, _ := meta[AnnotationParams].(Params)
+			return ActionDef{Type: typeName, Params: params}
+		}
+	}
+
+	for _, exporter := range r.exporters {
+		if def, ok := exporter(action); ok {
+			return def
+		}
+	}
+
+	return ActionDef{Type: "opaque", Params: Params{"go_type": fmt.Sprintf("%T", action), "name": routine.ActionName(action)}}
+
+}
@@ -0,0 +1,71 @@
+package routine
+
+// DefaultHistorySize is the number of transitions a Routine keeps in its history by default (see Routine.History()).
+const DefaultHistorySize = 256
+
+// Transition represents a single transition of a Block from one of its Actions (i.e. an Action that was polled and
+// returned something other than FlowIdle, or that caused the Block to jump).
+type Transition struct {
+	Tick       int    // The Routine's tick (i.e. the number of Update() calls) at which the transition happened
+	Block      any    // The ID of the Block
+	Index      int    // The index of the Action in the Block
+	Action     Action // The Action
+	ActionName string // The name of the Action (see ActionName())
+	Flow       Flow   // The Flow the Block followed as a result of polling the Action
+}
+
+// history is a ring buffer of the most recent Transitions.
+type history struct {
+	entries []Transition
+	next    int
+	count   int
+}
+
+func (h *history) record(t Transition) {
+	if len(h.entries) == 0 {
+		return
+	}
+	h.entries[h.next] = t
+	h.next = (h.next + 1) % len(h.entries)
+	if h.count < len(h.entries) {
+		h.count++
+	}
+}
+
+// SetHistorySize sets the number of transitions the Routine keeps in its history (see Routine.History()), clearing it.
+// A size of 0 disables the history. By default, this is DefaultHistorySize.
+func (r *Routine) SetHistorySize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	r.history = history{entries: make([]Transition, size)}
+}
+
+// History returns the Routine's most recent Block transitions, oldest first. This is recorded at all times (up to the
+// size set with Routine.SetHistorySize()), so that when something goes wrong, what the Routine did recently can be
+// dumped without having enabled a tracer in advance.
+func (r *Routine) History() []Transition {
+
+	h := &r.history
+	transitions := make([]Transition, 0, h.count)
+
+	if h.count == 0 {
+		return transitions
+	}
+
+	start := (h.next - h.count + len(h.entries)) % len(h.entries)
+	for i := 0; i < h.count; i++ {
+		t := h.entries[(start+i)%len(h.entries)]
+		t.ActionName = ActionName(t.Action)
+		transitions = append(transitions, t)
+	}
+
+	return transitions
+
+}
+
+// ClearHistory clears the Routine's history of transitions.
+func (r *Routine) ClearHistory() {
+	r.history.next = 0
+	r.history.count = 0
+}
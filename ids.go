@@ -0,0 +1,64 @@
+package routine
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchID returns if the given Block ID matches the given pattern. Most IDs simply match if they're equal to the pattern.
+// However, string IDs can be hierarchical, using forward slashes to separate levels (e.g. "cutscene/intro/line3"),
+// and string patterns can use wildcards to match multiple IDs at once. A level consisting of a single asterisk matches
+// exactly one level ("cutscene/*" matches "cutscene/intro", but not "cutscene/intro/line3"), while a level consisting of
+// two asterisks matches any number of levels, including none ("cutscene/**" matches both "cutscene/intro" and
+// "cutscene/intro/line3").
+//
+// Within a level, asterisks, question marks, and character classes (e.g. [abc]) can also be used, following the rules of path.Match().
+func MatchID(pattern, id any) bool {
+
+	if pattern == id {
+		return true
+	}
+
+	patternString, ok := pattern.(string)
+	if !ok || !strings.ContainsAny(patternString, "*?[") {
+		return false
+	}
+
+	idString, ok := id.(string)
+	if !ok {
+		return false
+	}
+
+	return matchSegments(strings.Split(patternString, "/"), strings.Split(idString, "/"))
+
+}
+
+func matchSegments(pattern, id []string) bool {
+
+	for len(pattern) > 0 {
+
+		if pattern[0] == "**" {
+			for i := 0; i <= len(id); i++ {
+				if matchSegments(pattern[1:], id[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(id) == 0 {
+			return false
+		}
+
+		if matched, err := path.Match(pattern[0], id[0]); err != nil || !matched {
+			return false
+		}
+
+		pattern = pattern[1:]
+		id = id[1:]
+
+	}
+
+	return len(id) == 0
+
+}
@@ -0,0 +1,44 @@
+package routine
+
+// Send sends a message to the Routine's inbox, where it waits until it is received (see Routine.Receive()). This allows
+// scripted entities running separate Routines to communicate (e.g. a guard's Routine telling a door's Routine to open).
+// Send is safe to call from other goroutines.
+func (r *Routine) Send(message any) {
+	r.inboxMutex.Lock()
+	r.inbox = append(r.inbox, message)
+	r.inboxMutex.Unlock()
+}
+
+// Receive removes the oldest message in the Routine's inbox for which match returns true, and returns it along with
+// true. If match is nil, the oldest message is received regardless of its value. If no message matches, Receive
+// returns nil and false.
+func (r *Routine) Receive(match func(message any) bool) (any, bool) {
+
+	r.inboxMutex.Lock()
+	defer r.inboxMutex.Unlock()
+
+	for i, message := range r.inbox {
+		if match == nil || match(message) {
+			r.inbox = append(r.inbox[:i], r.inbox[i+1:]...)
+			return message, true
+		}
+	}
+
+	return nil, false
+
+}
+
+// PendingMessages returns the number of messages waiting in the Routine's inbox.
+func (r *Routine) PendingMessages() int {
+	r.inboxMutex.Lock()
+	defer r.inboxMutex.Unlock()
+	return len(r.inbox)
+}
+
+// ClearInbox removes all messages from the Routine's inbox. Messages that are never received stay in the inbox
+// indefinitely, so this can be used to discard them.
+func (r *Routine) ClearInbox() {
+	r.inboxMutex.Lock()
+	r.inbox = nil
+	r.inboxMutex.Unlock()
+}
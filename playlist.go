@@ -0,0 +1,103 @@
+package routine
+
+// Playlist represents a controller Block that runs a sequence of other Blocks in order, waiting for each to finish
+// before running the next. This is useful for "chapter"-like structures, like in tutorials or story scenes.
+type Playlist struct {
+	block    *Block
+	blockIDs []any
+}
+
+type playlistEntry struct {
+	blockID any
+	started bool
+}
+
+func (p *playlistEntry) Init(block *Block) {
+	p.started = false
+}
+
+func (p *playlistEntry) Poll(block *Block) Flow {
+
+	r := block.Routine()
+	r.loadBlocks([]any{p.blockID})
+
+	entry := r.BlockByID(p.blockID)
+
+	if entry == nil {
+		return FlowNext
+	}
+
+	if !p.started {
+		p.started = true
+		entry.Restart()
+		entry.Run()
+		return FlowIdle
+	}
+
+	if entry.Running() {
+		return FlowIdle
+	}
+
+	return FlowNext
+
+}
+
+// DefinePlaylist defines a Block with the given ID that, when run, runs each of the Blocks with the given IDs in order,
+// restarting each one and waiting for it to finish before moving on to the next. The Block finishes after the last
+// listed Block finishes. Listed Blocks that don't exist when the playlist reaches them are loaded using the Routine's
+// block loader (see Routine.SetBlockLoader()), and skipped if they can't be loaded.
+// DefinePlaylist returns a Playlist, which can be used to control the playlist.
+func (r *Routine) DefinePlaylist(id any, blockIDs ...any) *Playlist {
+
+	playlist := &Playlist{
+		blockIDs: append([]any{}, blockIDs...),
+	}
+
+	entries := make([]Action, 0, len(blockIDs))
+	for _, blockID := range blockIDs {
+		entries = append(entries, &playlistEntry{blockID: blockID})
+	}
+
+	playlist.block = r.Define(id, entries...)
+
+	return playlist
+
+}
+
+// Block returns the controller Block of the Playlist.
+func (p *Playlist) Block() *Block {
+	return p.block
+}
+
+// Current returns the index of the currently playing entry in the Playlist, or -1 if the Playlist isn't running.
+func (p *Playlist) Current() int {
+	if p.block == nil || !p.block.Running() {
+		return -1
+	}
+	return p.block.Index()
+}
+
+// CurrentID returns the ID of the currently playing Block in the Playlist, or nil if the Playlist isn't running.
+func (p *Playlist) CurrentID() any {
+	if current := p.Current(); current >= 0 {
+		return p.blockIDs[current]
+	}
+	return nil
+}
+
+// Next stops the currently playing Block, so that the Playlist moves on to the next one.
+func (p *Playlist) Next() {
+	if id := p.CurrentID(); id != nil {
+		if block := p.block.Routine().BlockByID(id); block != nil {
+			block.Stop()
+		}
+	}
+}
+
+// Skip stops the currently playing Block as well as the Playlist itself, skipping any remaining entries.
+func (p *Playlist) Skip() {
+	p.Next()
+	if p.block != nil {
+		p.block.Stop()
+	}
+}
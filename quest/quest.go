@@ -0,0 +1,218 @@
+// quest is a package that provides helpers for tracking quests or objectives using a Routine. Each Objective is
+// represented by a Block that waits until the Objective's completion condition is met, keeping its progress and
+// completion state in the Routine's Properties, and publishing an event when it completes.
+package quest
+
+import (
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/actions"
+)
+
+type blockID struct{ objective any }
+type progressKey struct{ objective any }
+type completedKey struct{ objective any }
+type completedTopic struct{ objective any }
+
+// BlockID returns the ID of the Block that tracks the Objective with the given ID.
+func BlockID(objectiveID any) any { return blockID{objectiveID} }
+
+// ProgressKey returns the key of the Routine property that holds the progress (a float64 ranging from 0 to 1) of the
+// Objective with the given ID.
+func ProgressKey(objectiveID any) any { return progressKey{objectiveID} }
+
+// CompletedKey returns the key of the Routine property that holds whether the Objective with the given ID has been
+// completed (a bool).
+func CompletedKey(objectiveID any) any { return completedKey{objectiveID} }
+
+// CompletedTopic returns the EventBus topic that is published to when the Objective with the given ID is completed.
+// The event's payload is the Objective's ID. This can be waited on using actions.NewWaitForEvent().
+func CompletedTopic(objectiveID any) any { return completedTopic{objectiveID} }
+
+// Objective represents a single objective, tracked by a Block in its Tracker's Routine.
+type Objective struct {
+	ID         any
+	tracker    *Tracker
+	block      *routine.Block
+	condition  func() bool
+	progress   func() float64
+	onComplete func()
+}
+
+// SetProgress sets a function that returns the Objective's progress, ranging from 0 to 1. While the Objective is
+// being tracked, its progress is stored in the Routine's Properties each frame (see ProgressKey()). If no progress function
+// is set, the Objective's progress is 0 until it is completed, and 1 afterwards.
+func (o *Objective) SetProgress(progress func() float64) *Objective {
+	o.progress = progress
+	return o
+}
+
+// SetOnComplete sets a function to be called when the Objective is completed.
+func (o *Objective) SetOnComplete(onComplete func()) *Objective {
+	o.onComplete = onComplete
+	return o
+}
+
+// Start starts tracking the Objective by running its Block.
+func (o *Objective) Start() *Objective {
+	o.block.Run()
+	return o
+}
+
+// Block returns the Block that tracks the Objective.
+func (o *Objective) Block() *routine.Block {
+	return o.block
+}
+
+// Completed returns if the Objective has been completed.
+func (o *Objective) Completed() bool {
+	completed, _ := o.tracker.Routine.Properties().Get(CompletedKey(o.ID)).(bool)
+	return completed
+}
+
+// Progress returns the Objective's progress, ranging from 0 to 1.
+func (o *Objective) Progress() float64 {
+	progress, _ := o.tracker.Routine.Properties().Get(ProgressKey(o.ID)).(float64)
+	return progress
+}
+
+// Reset resets the Objective's progress and completion state, and stops its Block.
+func (o *Objective) Reset() {
+	o.block.Stop()
+	props := o.tracker.Routine.Properties()
+	props.Set(ProgressKey(o.ID), 0.0)
+	props.Set(CompletedKey(o.ID), false)
+}
+
+func (o *Objective) update() bool {
+
+	if o.progress != nil {
+		o.tracker.Routine.Properties().Set(ProgressKey(o.ID), clamp(o.progress()))
+	}
+
+	return o.condition()
+
+}
+
+func (o *Objective) complete() {
+
+	props := o.tracker.Routine.Properties()
+	props.Set(ProgressKey(o.ID), 1.0)
+	props.Set(CompletedKey(o.ID), true)
+
+	if o.onComplete != nil {
+		o.onComplete()
+	}
+
+	if o.tracker.Bus != nil {
+		o.tracker.Bus.Publish(CompletedTopic(o.ID), o.ID)
+	}
+
+}
+
+func clamp(value float64) float64 {
+	if value < 0 {
+		return 0
+	} else if value > 1 {
+		return 1
+	}
+	return value
+}
+
+// Tracker tracks Objectives using Blocks in a Routine.
+type Tracker struct {
+	Routine    *routine.Routine
+	Bus        *actions.EventBus // The EventBus completion events are published to; can be nil.
+	objectives []*Objective
+}
+
+// NewTracker creates a new Tracker that tracks Objectives using Blocks in the given Routine, publishing completion
+// events to the given EventBus (which can be nil).
+func NewTracker(r *routine.Routine, bus *actions.EventBus) *Tracker {
+	return &Tracker{
+		Routine: r,
+		Bus:     bus,
+	}
+}
+
+// Add adds an Objective with the given ID to the Tracker, defining a Block in the Tracker's Routine that waits until
+// the condition function returns true, and then marks the Objective as completed. The Objective isn't tracked until
+// it is started using Objective.Start(). If an Objective with the given ID already exists, it is replaced. If the Block
+// can't be defined (e.g. because the Routine's DefinePolicy doesn't allow replacing the existing Objective's Block; see
+// routine.Routine.DefineE()), the error is returned and the Tracker is left unchanged.
+func (t *Tracker) Add(id any, condition func() bool) (*Objective, error) {
+
+	objective := &Objective{
+		ID:        id,
+		tracker:   t,
+		condition: condition,
+	}
+
+	block, err := t.Routine.DefineE(BlockID(id),
+
+		actions.NewWaitUntil(objective.update),
+
+		actions.NewFunction(func(block *routine.Block) routine.Flow {
+			objective.complete()
+			return routine.FlowFinish
+		}),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	objective.block = block
+
+	props := t.Routine.Properties()
+	props.Set(ProgressKey(id), 0.0)
+	props.Set(CompletedKey(id), false)
+
+	for i, o := range t.objectives {
+		if o.ID == id {
+			t.objectives = append(t.objectives[:i], t.objectives[i+1:]...)
+			break
+		}
+	}
+
+	t.objectives = append(t.objectives, objective)
+
+	return objective, nil
+
+}
+
+// Objective returns the Objective with the given ID, or nil if no such Objective exists.
+func (t *Tracker) Objective(id any) *Objective {
+	for _, o := range t.objectives {
+		if o.ID == id {
+			return o
+		}
+	}
+	return nil
+}
+
+// Objectives returns all Objectives in the Tracker, in the order they were added.
+func (t *Tracker) Objectives() []*Objective {
+	return append([]*Objective{}, t.objectives...)
+}
+
+// AllCompleted returns if all Objectives with the given IDs have been completed. If no IDs are given, AllCompleted
+// returns if all Objectives in the Tracker have been completed.
+func (t *Tracker) AllCompleted(ids ...any) bool {
+
+	if len(ids) == 0 {
+		for _, o := range t.objectives {
+			if !o.Completed() {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, id := range ids {
+		if o := t.Objective(id); o == nil || !o.Completed() {
+			return false
+		}
+	}
+	return true
+
+}
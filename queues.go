@@ -0,0 +1,37 @@
+package routine
+
+// Enqueue appends the given value to the end of the queue with the given ID, creating the queue if it doesn't exist.
+// Queues let producer Blocks feed work to consumer Blocks (see actions.NewEnqueue() and actions.NewDequeueInto()).
+func (r *Routine) Enqueue(queueID any, value any) {
+	if r.queues == nil {
+		r.queues = map[any][]any{}
+	}
+	r.queues[queueID] = append(r.queues[queueID], value)
+}
+
+// Dequeue removes the value at the front of the queue with the given ID and returns it along with true. If the queue is
+// empty (or doesn't exist), Dequeue returns nil and false.
+func (r *Routine) Dequeue(queueID any) (any, bool) {
+
+	queue := r.queues[queueID]
+	if len(queue) == 0 {
+		return nil, false
+	}
+
+	value := queue[0]
+	queue[0] = nil
+	r.queues[queueID] = queue[1:]
+
+	return value, true
+
+}
+
+// QueueLen returns the number of values waiting in the queue with the given ID.
+func (r *Routine) QueueLen(queueID any) int {
+	return len(r.queues[queueID])
+}
+
+// ClearQueue removes all values from the queue with the given ID.
+func (r *Routine) ClearQueue(queueID any) {
+	delete(r.queues, queueID)
+}
@@ -0,0 +1,48 @@
+package routine
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+// SetSeed seeds the Routine's random source (see Routine.Rand()), so that Actions that use it behave deterministically
+// (e.g. for replays or tests). This also resets the Blocks' own random sources (see Block.Rand()).
+func (r *Routine) SetSeed(seed int64) {
+	r.seed = seed
+	r.rand = rand.New(rand.NewSource(seed))
+	for _, block := range r.blocks {
+		block.rand = nil
+	}
+}
+
+// Seed returns the seed of the Routine's random source. If the Routine hasn't been seeded using Routine.SetSeed(), it
+// is seeded with the current time when its random source is first used.
+func (r *Routine) Seed() int64 {
+	r.Rand()
+	return r.seed
+}
+
+// Rand returns the Routine's random source, which Actions that need randomness (like actions.NewWaitJitter()) should
+// use rather than the global one, so that they can be made deterministic by seeding the Routine (see Routine.SetSeed()).
+// Like the Routine itself, the random source isn't safe for concurrent use.
+func (r *Routine) Rand() *rand.Rand {
+	if r.rand == nil {
+		r.SetSeed(time.Now().UnixNano())
+	}
+	return r.rand
+}
+
+// Rand returns the Block's own random source, which is seeded from the Routine's seed (see Routine.SetSeed()) and the
+// Block's ID. Actions that use it rather than the Routine's random source (see Routine.Rand()) don't perturb the
+// randomness of other Blocks, so each Block's behavior stays deterministic even if the order in which Actions across
+// Blocks use randomness changes - which is important for replay stability.
+func (b *Block) Rand() *rand.Rand {
+	if b.rand == nil {
+		hash := fnv.New64a()
+		fmt.Fprint(hash, b.ID)
+		b.rand = rand.New(rand.NewSource(b.routine.Seed() ^ int64(hash.Sum64())))
+	}
+	return b.rand
+}
@@ -0,0 +1,87 @@
+package routine
+
+import "fmt"
+
+// ReadOnlyProperties is a read-only view of a Properties object (see Properties.ReadOnly()). It reflects changes made to
+// the underlying Properties, but can't be used to make them; its mutating methods panic with an error wrapping
+// ErrReadOnlyProperties instead (or report it, for views bound to a Routine in strict mode; see
+// ReadOnlyProperties.For()). This allows systems to hand script-visible configuration to Blocks with confidence that
+// Actions won't accidentally mutate it.
+type ReadOnlyProperties struct {
+	props   Properties
+	routine *Routine
+}
+
+// ReadOnly returns a read-only view of the Properties object.
+func (p Properties) ReadOnly() ReadOnlyProperties {
+	return ReadOnlyProperties{props: p}
+}
+
+// For returns a copy of the view bound to the given Routine. When the Routine is in strict mode (see
+// Routine.SetStrict()), attempted mutations are reported to its error handler (see Routine.SetErrorHandler()) and
+// ignored, rather than panicking.
+func (v ReadOnlyProperties) For(r *Routine) ReadOnlyProperties {
+	v.routine = r
+	return v
+}
+
+// Get returns the value associated with the given property identifier.
+func (v ReadOnlyProperties) Get(propName any) any {
+	return v.props.Get(propName)
+}
+
+// Has returns if the underlying Properties object has a property associated with the given identifier.
+func (v ReadOnlyProperties) Has(propName any) bool {
+	return v.props.Has(propName)
+}
+
+// Keys returns the identifiers of the properties, in no particular order.
+func (v ReadOnlyProperties) Keys() []any {
+	return v.props.Keys()
+}
+
+// Len returns the number of properties.
+func (v ReadOnlyProperties) Len() int {
+	return v.props.Len()
+}
+
+// Copy returns a mutable (shallow) copy of the underlying Properties object.
+func (v ReadOnlyProperties) Copy() Properties {
+	p := Properties{}
+	p.Merge(v.props)
+	return p
+}
+
+// Init would initialize a property; as the view is read-only, it fails instead.
+func (v ReadOnlyProperties) Init(propName any, toValue any) { v.mutate("Init", propName) }
+
+// Set would set a property; as the view is read-only, it fails instead.
+func (v ReadOnlyProperties) Set(propName any, value any) { v.mutate("Set", propName) }
+
+// Delete would delete a property; as the view is read-only, it fails instead.
+func (v ReadOnlyProperties) Delete(propName any) { v.mutate("Delete", propName) }
+
+// Clear would clear the properties; as the view is read-only, it fails instead.
+func (v ReadOnlyProperties) Clear() { v.mutate("Clear", nil) }
+
+// Merge would merge other into the properties; as the view is read-only, it fails instead.
+func (v ReadOnlyProperties) Merge(other Properties) { v.mutate("Merge", nil) }
+
+// CopyFrom would replace the properties; as the view is read-only, it fails instead.
+func (v ReadOnlyProperties) CopyFrom(other Properties) { v.mutate("CopyFrom", nil) }
+
+func (v ReadOnlyProperties) mutate(method string, propName any) {
+
+	err := fmt.Errorf("%w: %s", ErrReadOnlyProperties, method)
+	if propName != nil {
+		err = fmt.Errorf("%w: %s(%v)", ErrReadOnlyProperties, method, propName)
+	}
+
+	if v.routine != nil && v.routine.strict {
+		v.routine.ReportError(err)
+		return
+	}
+
+	panic(err)
+
+}
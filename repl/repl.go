@@ -0,0 +1,241 @@
+// repl is a package that provides a small interactive console that attaches to a Routine, allowing you to
+// inspect and control it while it runs. This is primarily useful while developing scripted content in terminal-based
+// prototypes, like the provided examples.
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/solarlune/routine"
+)
+
+// Console represents an interactive console attached to a Routine. Commands are read from an input stream on
+// a separate goroutine, but they're only executed when Console.Update() is called, so the Routine is never touched
+// outside of the main thread.
+type Console struct {
+	Routine *routine.Routine
+	out     io.Writer
+	in      io.Reader
+	lines   chan string
+	started bool
+	broken  bool
+	steps   int
+}
+
+// New creates a new Console attached to the given Routine, reading commands from in and writing output to out.
+// Generally, in would be os.Stdin and out would be os.Stdout.
+func New(r *routine.Routine, in io.Reader, out io.Writer) *Console {
+	return &Console{
+		Routine: r,
+		in:      in,
+		out:     out,
+		lines:   make(chan string, 64),
+	}
+}
+
+// Start starts reading commands from the Console's input stream. Commands are queued until Console.Update() is called.
+func (c *Console) Start() {
+
+	if c.started {
+		return
+	}
+
+	c.started = true
+
+	go func() {
+		scanner := bufio.NewScanner(c.in)
+		for scanner.Scan() {
+			c.lines <- scanner.Text()
+		}
+		close(c.lines)
+	}()
+
+}
+
+// Update executes any queued commands and then updates the Routine. Call this once per frame in place of
+// Routine.Update(). If the Console is in break mode (see the "break" command), the Routine is only updated when
+// stepped.
+func (c *Console) Update() {
+
+	pending := true
+
+	for pending {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				pending = false
+				c.lines = nil
+				break
+			}
+			if err := c.Exec(line); err != nil {
+				fmt.Fprintln(c.out, "error:", err)
+			}
+		default:
+			pending = false
+		}
+	}
+
+	if !c.broken {
+		c.Routine.Update()
+	} else if c.steps > 0 {
+		c.Routine.Update()
+		c.steps--
+	}
+
+}
+
+// Broken returns if the Console is in break mode, in which case the Routine is only updated when stepped.
+func (c *Console) Broken() bool {
+	return c.broken
+}
+
+// Exec executes a single command line. Recognized commands are:
+//
+//	list                 - Lists all Blocks in the Routine, their states, and their current Action indices.
+//	run [blocks...]      - Runs the given Blocks (or all Blocks if none are given).
+//	pause [blocks...]    - Pauses the given Blocks (or all Blocks if none are given).
+//	stop [blocks...]     - Stops the given Blocks (or all Blocks if none are given).
+//	restart [blocks...]  - Restarts the given Blocks (or all Blocks if none are given).
+//	jump block label     - Jumps the given Block to the Label with the given ID.
+//	props                - Lists the Routine's Properties.
+//	break                - Enters break mode, halting automatic updates of the Routine.
+//	continue             - Leaves break mode, resuming automatic updates of the Routine.
+//	step [n]             - Updates the Routine n times (or once if n isn't given); enters break mode if necessary.
+//	help                 - Lists the available commands.
+//
+// Block and Label IDs are matched against their printed (fmt.Sprint()) representation.
+func (c *Console) Exec(line string) error {
+
+	fields := strings.Fields(line)
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	command, args := fields[0], fields[1:]
+
+	switch command {
+
+	case "list", "ls":
+		for _, block := range c.Routine.BlockList() {
+			state := "stopped"
+			if block.Running() {
+				state = "running"
+			}
+			fmt.Fprintf(c.out, "%v\t%s\t%d/%d\n", block.ID, state, block.Index(), block.ActionCount())
+		}
+
+	case "run", "pause", "stop", "restart":
+
+		ids, err := c.blockIDs(args)
+		if err != nil {
+			return err
+		}
+
+		switch command {
+		case "run":
+			c.Routine.Run(ids...)
+		case "pause":
+			c.Routine.Pause(ids...)
+		case "stop":
+			c.Routine.Stop(ids...)
+		case "restart":
+			c.Routine.Restart(ids...)
+		}
+
+	case "jump":
+
+		if len(args) != 2 {
+			return fmt.Errorf("usage: jump block label")
+		}
+
+		block := c.block(args[0])
+		if block == nil {
+			return fmt.Errorf("no block with ID %s", args[0])
+		}
+
+		for _, action := range block.ActionList() {
+			if label, ok := action.(routine.ActionIdentifiable); ok && fmt.Sprint(label.ID()) == args[1] {
+				block.JumpTo(label.ID())
+				return nil
+			}
+		}
+
+		return fmt.Errorf("no label with ID %s in block %s", args[1], args[0])
+
+	case "props":
+
+		props := *c.Routine.Properties()
+
+		keys := make([]string, 0, len(props))
+		values := map[string]any{}
+		for k, v := range props {
+			key := fmt.Sprint(k)
+			keys = append(keys, key)
+			values[key] = v
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fmt.Fprintf(c.out, "%s\t%v\n", k, values[k])
+		}
+
+	case "break":
+		c.broken = true
+
+	case "continue", "cont":
+		c.broken = false
+		c.steps = 0
+
+	case "step":
+
+		count := 1
+
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %s", args[0])
+			}
+			count = n
+		}
+
+		c.broken = true
+		c.steps += count
+
+	case "help":
+		fmt.Fprintln(c.out, "commands: list, run [blocks...], pause [blocks...], stop [blocks...], restart [blocks...], jump block label, props, break, continue, step [n], help")
+
+	default:
+		return fmt.Errorf("unknown command %s", command)
+
+	}
+
+	return nil
+
+}
+
+func (c *Console) block(name string) *routine.Block {
+	for _, block := range c.Routine.BlockList() {
+		if fmt.Sprint(block.ID) == name {
+			return block
+		}
+	}
+	return nil
+}
+
+func (c *Console) blockIDs(names []string) ([]any, error) {
+	ids := make([]any, 0, len(names))
+	for _, name := range names {
+		block := c.block(name)
+		if block == nil {
+			return nil, fmt.Errorf("no block with ID %s", name)
+		}
+		ids = append(ids, block.ID)
+	}
+	return ids, nil
+}
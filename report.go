@@ -0,0 +1,24 @@
+package routine
+
+// UpdateReport represents a summary of what happened in a single Routine.Update() call.
+type UpdateReport struct {
+	Started  []any // The IDs of Blocks that were updated this frame, but not in the previous one.
+	Advanced []any // The IDs of Blocks that moved on to another Action (or jumped) this frame.
+	Finished []any // The IDs of Blocks that finished (and so deactivated) this frame.
+	Failed   []any // The IDs of Blocks that failed (see Block.Fail()) this frame. These are also included in Finished.
+	Polled   int   // The number of Actions that were polled this frame.
+}
+
+// Changed returns if anything changed in the Routine as a result of the Update() call; that is to say, if any Blocks
+// started, advanced, or finished.
+func (u UpdateReport) Changed() bool {
+	return len(u.Started) > 0 || len(u.Advanced) > 0 || len(u.Finished) > 0
+}
+
+func (u *UpdateReport) reset() {
+	u.Started = u.Started[:0]
+	u.Advanced = u.Advanced[:0]
+	u.Finished = u.Finished[:0]
+	u.Failed = u.Failed[:0]
+	u.Polled = 0
+}
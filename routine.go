@@ -1,6 +1,16 @@
 // routine is a package for creating sequences of events, primarily for game development in Golang.
 package routine
 
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // Properties represents a kind of "local memory" for an Execution object.
 type Properties map[any]any
 
@@ -39,8 +49,40 @@ func (p *Properties) Clear() {
 }
 
 // Delete deletes a key out of the properties map.
-func (p *Properties) Delete(keyName string) {
-	delete(*p, keyName)
+func (p *Properties) Delete(propName any) {
+	delete(*p, propName)
+}
+
+// Keys returns the identifiers of the properties in the Properties object, in no particular order.
+func (p Properties) Keys() []any {
+	keys := make([]any, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Len returns the number of properties in the Properties object.
+func (p Properties) Len() int {
+	return len(p)
+}
+
+// Merge sets the properties in the Properties object to the values in other,
+// overwriting any that already exist. Properties not in other are left alone.
+func (p *Properties) Merge(other Properties) {
+	if *p == nil {
+		*p = make(Properties, len(other))
+	}
+	for k, v := range other {
+		(*p)[k] = v
+	}
+}
+
+// CopyFrom replaces the contents of the Properties object with a (shallow)
+// copy of other.
+func (p *Properties) CopyFrom(other Properties) {
+	p.Clear()
+	p.Merge(other)
 }
 
 // Flow is simply a uint8, and represents what a Routine should do following a Action's action.
@@ -50,19 +92,51 @@ const (
 	// FlowIdle means that the Routine should cycle and do the same Action again the following Update() cycle.
 	FlowIdle Flow = iota
 	// FlowNext means that the Routine should move on to the next Action in the Block.
-	// If this is returned from the last Action in a Block, the Block will loop.
+	// If this is returned from the last Action in a Block, what happens depends on the Block's EndBehavior (see
+	// Block.SetEndBehavior()); by default, the Block stops.
 	FlowNext
 	// FlowFinish indicates the Block should finish its execution, deactivating afterwards.
 	FlowFinish
+	// FlowRestartBlock indicates the Block should restart from its first Action, continuing to run on the following
+	// frame. Unlike jumping to the first Action, this also resets the Block's local Properties, result, Label
+	// crossings, and timers.
+	FlowRestartBlock
+	// FlowFail indicates the Block has failed, stopping it with an error (see Block.Fail()). Unlike finishing, the Block
+	// is then marked as failed (see Block.Failed()) until it's run or restarted again.
+	FlowFail
+	// FlowSkip means that the Routine should move on to the next Action in the Block like FlowNext, but without counting
+	// the current Action as executed (e.g. in the UpdateReport's Polled count). This is used by Actions that are
+	// conditionally disabled (see actions.NewOnlyIf()). Containers of Actions (like actions.NestedCollection) treat it
+	// like FlowNext.
+	FlowSkip
 )
 
+func (f Flow) String() string {
+	switch f {
+	case FlowIdle:
+		return "Idle"
+	case FlowNext:
+		return "Next"
+	case FlowFinish:
+		return "Finish"
+	case FlowRestartBlock:
+		return "RestartBlock"
+	case FlowFail:
+		return "Fail"
+	case FlowSkip:
+		return "Skip"
+	}
+	return fmt.Sprintf("Flow(%d)", uint8(f))
+}
+
 // Action is an interface that represents an object that can Action and direct the flow of a Routine.
 type Action interface {
-	Init(block *Block)      // The Init function is called when a Action is switched to.
+	Init(block *Block)      // The Init function is called when a Action is switched to, right before it is first polled.
 	Poll(block *Block) Flow // The Poll function is called every frame and can return a Flow, indicating what the Routine should do next.
 }
 
 // ActionCollectionable identifies an interface for an Action that allows it to return a slice of Actions to be added to Blocks, Gates, or Collections in definition.
+// Flattening is recursive, so any ActionCollectionables returned are also replaced by the Actions they contain (see FlattenActions()).
 type ActionCollectionable interface {
 	Actions() []Action
 }
@@ -72,17 +146,117 @@ type ActionIdentifiable interface {
 	ID() any
 }
 
+// ActionWakeable identifies an interface for an Action that idles until a specific point in time. This allows a
+// Routine to report when it next needs to be updated (see Routine.NextWakeTime()). The time is on the Routine's Clock,
+// and so should account for the Routine's time scale.
+type ActionWakeable interface {
+	WakeTime() time.Time
+}
+
+// ActionEndable identifies an interface for an Action that needs to know when its Block moves off of it, for any reason
+// (moving on, jumping, finishing, failing, stopping, restarting, or being removed), so that Actions that acquire
+// resources (like audio handles or input locks) can reliably release them. End is called exactly once for each time
+// the Action is initialized (and only if it was), before the next Action is initialized.
+type ActionEndable interface {
+	End(block *Block)
+}
+
+// ActionNameable identifies an interface for an Action that has a human-readable name (e.g. "Wait 2s"), which is used
+// in traces and debugging tools (see ActionName()).
+type ActionNameable interface {
+	Name() string
+}
+
+// ActionName returns the name of the given Action - its name if it's an ActionNameable, or otherwise its type.
+func ActionName(action Action) string {
+	if nameable, ok := action.(ActionNameable); ok {
+		return nameable.Name()
+	}
+	return fmt.Sprintf("%T", action)
+}
+
+// EndBehavior is simply a uint8, and represents what a Block does when it moves on from its last Action.
+type EndBehavior uint8
+
+const (
+	// EndStop means that the Block stops, starting again from its first Action when it's next run. This is the default.
+	EndStop EndBehavior = iota
+	// EndLoop means that the Block loops, continuing from its first Action on the following Update() call.
+	EndLoop
+	// EndHold means that the Block stays on its last Action, remaining running but no longer polling it (i.e. it's
+	// "complete but resident"; see Block.Held()) until it's continued (see Block.Continue()), restarted, stopped, or
+	// its index is set.
+	EndHold
+)
+
+// SameIndexBehavior is simply a uint8, and represents what a Block does when its index is set to the index it's already
+// on (see Block.SetSameIndexBehavior()).
+type SameIndexBehavior uint8
+
+const (
+	// SameIndexIgnore means that setting a Block's index to its current index does nothing, leaving the current Action
+	// running as it was. This is the default.
+	SameIndexIgnore SameIndexBehavior = iota
+	// SameIndexReInit means that setting a Block's index to its current index initializes the current Action again (see
+	// Block.ReInitCurrent()), so that it starts over.
+	SameIndexReInit
+)
+
+type labelCrossing struct {
+	tick    int
+	elapsed time.Duration
+}
+
 // Block represents a block of Actions. Blocks execute Actions in sequence, and have an ID that allows them to be
 // activated or deactivated at will by their owning Routine.
 type Block struct {
-	currentlyActive bool
-	active          bool
-	currentFrame    int // The current frame of the Block for the currently running Action.
-	ID              any
-	Actions         []Action
-	index           int
-	indexChanged    bool
-	routine         *Routine
+	// Deprecated: Actions mirrors the Block's list of Actions for code written against older versions, and will be
+	// removed in the next release; use Block.ActionList() and the Block's mutator functions (like Block.SetActions())
+	// instead. A list assigned to it is adopted by the Block (without restarting it) the next time it's modified or
+	// its Routine is updated.
+	Actions []Action
+
+	currentlyActive  bool
+	active           bool
+	currentFrame     int           // The current frame of the Block for the currently running Action.
+	actionElapsed    time.Duration // The time the Block has spent on the currently running Action.
+	activeElapsed    time.Duration // The total time the Block has spent running.
+	runTime          time.Duration // The total time the Block has been updated for; unlike activeElapsed, it's never reset.
+	runFrames        int           // The number of Routine.Update() calls the Block has been updated in.
+	actionState      map[Action]any
+	ID               any
+	actions          []Action
+	index            int
+	indexChanged     bool
+	routine          *Routine
+	finishing        bool                  // Whether the Block should stop once its current Action completes.
+	source           string                // The file:line location of the Define() call that created the Block, if captured.
+	updatedLast      bool                  // Whether the Block was updated in the previous Routine.Update() call.
+	updatedThisFrame bool                  // Whether the Block has been updated in the current Routine.Update() call.
+	advanced         bool                  // Whether the Block moved to another Action in the current Routine.Update() call.
+	properties       *Properties           // The Block's local Properties.
+	result           any                   // The result last set by an Action through SetResult().
+	args             any                   // The arguments the Block was last run with through RunWith().
+	labelCrossings   map[any]labelCrossing // When each ActionIdentifiable in the Block was last polled, by ID.
+	labels           map[any]int           // A cache of the indices of ActionIdentifiables in the Block, by ID.
+	endBehavior      EndBehavior
+	err              error // The error the Block failed with, if any.
+	bookmarks        map[any]Bookmark
+	rand             *rand.Rand
+	autoRemove       bool          // Whether the Block is removed from the Routine once it stops (see Block.SetAutoRemove()).
+	stopped          bool          // Whether the Block has stopped since it was last run.
+	held             bool          // Whether the Block is holding at its end (see EndHold).
+	precondition     func() bool   // The condition the Block waits for before starting (see Block.SetPrecondition()).
+	preTimeout       time.Duration // How long the Block waits for its precondition before failing, if set.
+	preElapsed       time.Duration // How long the Block has waited for its precondition.
+	awaitingPre      bool          // Whether the Block is waiting for its precondition to pass.
+	sameIndex        SameIndexBehavior
+	needsInit        bool   // Whether the current Action must be initialized before it's next polled.
+	entered          Action // The Action that was last initialized, if it hasn't been ended yet (see ActionEndable).
+
+	skipMutex   sync.Mutex
+	skipPending bool
+	skipLabel   any
 }
 
 // SetIndex sets the index of the Action sequence of the Block to the value given.
@@ -90,315 +264,1698 @@ type Block struct {
 // slot.
 func (b *Block) SetIndex(index int) {
 
-	if index < 0 {
-		index = 0
+	if len(b.actions) == 0 {
+		b.index = 0
+		if b.routine.strict {
+			b.routine.ReportError(fmt.Errorf("%w: can't set index of block %v", ErrNoActions, b.ID))
+		}
+		return
 	}
 
-	if index > len(b.Actions)-1 {
-		index = len(b.Actions) - 1
+	if index < 0 || index > len(b.actions)-1 {
+		if b.routine.strict {
+			b.routine.ReportError(fmt.Errorf("%w: index %d in block %v with %d actions", ErrIndexOutOfRange, index, b.ID, len(b.actions)))
+		}
+		if index < 0 {
+			index = 0
+		} else {
+			index = len(b.actions) - 1
+		}
 	}
 
 	if b.index != index {
-
 		b.index = index
-		b.Actions[b.index].Init(b)
-		b.currentFrame = 0
-		if b.currentlyActive {
-			b.indexChanged = true
-		}
+		b.ReInitCurrent()
+	} else if b.sameIndex == SameIndexReInit {
+		b.ReInitCurrent()
+	}
+
+}
+
+// ReInitCurrent makes the Block initialize its current Action again before it's next polled, resetting the Block's frame
+// and time counters for it (see Block.CurrentFrame() and Block.CurrentActionElapsed()), so that the Action starts over
+// (e.g. to restart a Wait).
+func (b *Block) ReInitCurrent() {
+
+	if len(b.actions) == 0 {
+		return
+	}
 
+	b.held = false
+	b.endCurrent()
+	b.needsInit = true
+	b.resetFrame()
+	if b.currentlyActive {
+		b.indexChanged = true
 	}
 
 }
 
+// SetSameIndexBehavior sets what the Block does when its index is set to the index it's already on (see
+// Block.SetIndex()), including through jumping to a Label it's currently on. It returns the Block for chaining.
+func (b *Block) SetSameIndexBehavior(behavior SameIndexBehavior) *Block {
+	b.sameIndex = behavior
+	return b
+}
+
+// SameIndexBehavior returns what the Block does when its index is set to the index it's already on.
+func (b *Block) SameIndexBehavior() SameIndexBehavior {
+	return b.sameIndex
+}
+
 // JumpTo sets the Block's execution index to the index of a ActionLabel, using the label
 // provided.
 // If it finds the Label, then it will jump to and return that index. Otherwise, it will return -1.
+// Label indices are cached, so JumpTo is fast even in Blocks with many Actions; if you change a Label's ID
+// after the Block has been defined, call Block.RefreshLabels() afterwards.
 func (b *Block) JumpTo(labelID any) int {
-	for i, c := range b.Actions {
-		if label, ok := c.(ActionIdentifiable); ok {
-			if label.ID() == labelID {
-				b.SetIndex(i)
-				return i
-			}
-		}
+	if i := b.LabelIndex(labelID); i >= 0 {
+		b.SetIndex(i)
+		return i
 	}
 	return -1
 }
 
-// Index returns the index of the currently active Action in the Block.
-func (b *Block) Index() int {
-	return b.index
-}
+// LabelIndex returns the index of the first ActionIdentifiable (like a Label) in the Block with the given ID, or -1
+// if no such Action exists.
+func (b *Block) LabelIndex(labelID any) int {
 
-func (b *Block) update() {
+	if b.labels == nil {
+		b.labels = map[any]int{}
+		for i, c := range b.actions {
+			if label, ok := c.(ActionIdentifiable); ok {
+				if _, exists := b.labels[label.ID()]; !exists {
+					b.labels[label.ID()] = i
+				}
+			}
+		}
+	}
 
-	if !b.currentlyActive {
-		return
+	if i, ok := b.labels[labelID]; ok {
+		return i
 	}
+	return -1
 
-	b.indexChanged = false
+}
 
-	p := b.Actions[b.index].Poll(b)
+// RefreshLabels clears the Block's cache of Label indices, so that it is rebuilt the next time it is needed. This is
+// only necessary if a Label's ID is changed after the Block is defined; modifying the Block's Actions (e.g. with
+// Block.AddActions()) refreshes the cache automatically.
+func (b *Block) RefreshLabels() {
+	b.labels = nil
+}
 
-	b.currentFrame++
+// TicksSince returns the number of Routine.Update() calls since the Label with the given ID was last crossed (polled) in
+// the Block, or -1 if it hasn't been crossed yet. This can be used for logic like "if it's been more than 300 ticks
+// since the 'alerted' Label, calm down".
+func (b *Block) TicksSince(labelID any) int {
+	if crossing, ok := b.labelCrossings[labelID]; ok {
+		return b.routine.ticks - crossing.tick
+	}
+	return -1
+}
 
-	switch p {
-	case FlowNext:
+// TimeSince returns the Routine time (see Routine.Elapsed()) that has passed since the Label with the given ID was
+// last crossed (polled) in the Block, or -1 if it hasn't been crossed yet.
+func (b *Block) TimeSince(labelID any) time.Duration {
+	if crossing, ok := b.labelCrossings[labelID]; ok {
+		return b.routine.elapsed - crossing.elapsed
+	}
+	return -1
+}
 
-		if !b.indexChanged {
-			b.index++
-		}
+// SkipTo queues a jump to the Label with the given ID, which is applied at the start of the next Routine.Update() call.
+// Unlike JumpTo(), SkipTo is safe to call from outside of the Update loop (i.e. from UI callbacks or other goroutines),
+// so things like a "skip intro" button can fast-forward a Block without racing the currently running Action.
+// If multiple skips are queued before the next Update, only the last one is applied.
+func (b *Block) SkipTo(labelID any) {
+	b.skipMutex.Lock()
+	b.skipPending = true
+	b.skipLabel = labelID
+	b.skipMutex.Unlock()
+}
 
-		if b.index > len(b.Actions)-1 {
-			b.index = 0
-			b.active = false
-			b.currentlyActive = false
-		}
+func (b *Block) applySkip() {
 
-		b.Actions[b.index].Init(b)
-		b.currentFrame = 0
+	b.skipMutex.Lock()
+	pending, label := b.skipPending, b.skipLabel
+	b.skipPending = false
+	b.skipLabel = nil
+	b.skipMutex.Unlock()
 
-		if b.active {
-			b.update() // We call update again because it should move on unless it's idling, specifically
-		}
+	if pending {
+		b.JumpTo(label)
+	}
 
-	case FlowFinish:
-		b.index = 0
-		b.active = false // Restart if we're going to the next Action and we're at the end of the block
-		b.currentlyActive = false
-		b.Actions[b.index].Init(b)
-		b.currentFrame = 0
+}
 
-	case FlowIdle:
+// ActionList returns a copy of the Block's list of Actions. To modify the Block's Actions, use Block.SetActions(),
+// Block.AddActions(), Block.InsertActions(), or Block.RemoveAction(), so that the Block can keep its internal state
+// consistent. (This replaces the deprecated Block.Actions field.)
+func (b *Block) ActionList() []Action {
+	return append([]Action{}, b.actions...)
+}
 
-		if b.indexChanged {
-			b.Actions[b.index].Init(b)
-			b.currentFrame = 0
+// adoptActions adopts a list of Actions assigned to the deprecated Block.Actions field since the Block's Actions were
+// last modified, if any, and mirrors the Block's Actions to the field again.
+func (b *Block) adoptActions() {
+	if !sameSlice(b.Actions, b.actions) {
+		b.actions = b.routine.validActions(b.ID, FlattenActions(b.Actions...))
+		b.labels = nil
+		if b.index >= len(b.actions) {
+			b.index = 0
 		}
-
 	}
-
-}
-
-// Run runs the specified block.
-func (b *Block) Run() {
-	b.active = true
+	b.Actions = b.actions
 }
 
-// Running returns if the Block is active.
-func (b *Block) Running() bool {
-	return b.active
+// sameSlice returns if the given slices have the same length and backing array.
+func sameSlice[T any](a, b []T) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
 }
 
-// Pause pauses the specified block, so that it isn't active when the Routine is run. When it is run again, it resumes execution at its current action.
-func (b *Block) Pause() {
-	b.active = false
+// Action returns the Action at the given index in the Block, or nil if the index is out of range.
+func (b *Block) Action(index int) Action {
+	if index < 0 || index >= len(b.actions) {
+		return nil
+	}
+	return b.actions[index]
 }
 
-// Restart restarts the block.
-func (b *Block) Restart() {
-	b.index = -1
-	b.SetIndex(0)
+// ActionCount returns the number of Actions in the Block.
+func (b *Block) ActionCount() int {
+	return len(b.actions)
 }
 
-// Stop stops the Block, so that it restarts when it is run again.
-func (b *Block) Stop() {
-	b.Pause()
+// SetActions replaces the Block's Actions with the given ones, restarting the Block. As with Routine.Define(),
+// any ActionCollectionables passed are replaced with the Actions they contain.
+func (b *Block) SetActions(actions ...Action) {
+	b.actions = b.routine.validActions(b.ID, FlattenActions(actions...))
+	b.Actions = b.actions
+	b.labels = nil
 	b.Restart()
 }
 
-// Routine returns the currently running routine.
-func (b *Block) Routine() *Routine {
-	return b.routine
+// AddActions adds the given Actions to the end of the Block. As with Routine.Define(), any ActionCollectionables
+// passed are replaced with the Actions they contain.
+func (b *Block) AddActions(actions ...Action) {
+	b.adoptActions()
+	b.actions = append(b.actions, b.routine.validActions(b.ID, FlattenActions(actions...))...)
+	b.Actions = b.actions
+	b.labels = nil
 }
 
-// CurrentFrame returns the current frame of the Block's execution of the currently executed Action.
-// This increases by 1 every Routine.Update() call until the Block executes another Action.
-func (b *Block) CurrentFrame() int {
-	return b.currentFrame
-}
+// InsertActions inserts the given Actions into the Block at the given index (which is clamped to the range of the
+// Block's Actions). As with Routine.Define(), any ActionCollectionables passed are replaced with the Actions they contain.
+// If the Actions are inserted at or before the currently running Action after it has started, the Block's index is
+// shifted so that the same Action continues running; otherwise (e.g. if the Block hasn't started or has just been
+// restarted), the Block starts with whichever Action is at its index.
+func (b *Block) InsertActions(index int, actions ...Action) {
 
-// Routine represents a container to run Blocks of code.
-type Routine struct {
-	Blocks     []*Block
-	properties *Properties
-}
+	b.adoptActions()
 
-// New creates a new Routine.
-func New() *Routine {
-	r := &Routine{
-		Blocks:     []*Block{},
-		properties: &Properties{},
+	if index < 0 {
+		index = 0
+	} else if index > len(b.actions) {
+		index = len(b.actions)
 	}
-	return r
-}
 
-// Define defines a Block using the ID given and the list of Actions provided and adds it to the Routine.
-// The ID can be of any comparable type.
-// Define returns the new Block as well.
-// If a block with the given blockID already exists, Define will remove the previous one.
-func (r *Routine) Define(id any, Actions ...Action) *Block {
+	newActions := b.routine.validActions(b.ID, FlattenActions(actions...))
 
-	newActions := []Action{}
+	b.actions = append(b.actions[:index], append(newActions, b.actions[index:]...)...)
+	b.Actions = b.actions
+	b.labels = nil
 
-	for _, c := range Actions {
-		if collection, ok := c.(ActionCollectionable); ok {
-			newActions = append(newActions, collection.Actions()...)
-		} else {
-			newActions = append(newActions, c)
-		}
+	if index <= b.index && !b.needsInit && len(b.actions) > len(newActions) {
+		b.index += len(newActions)
 	}
 
-	newBlock := &Block{
-		ID:      id,
-		routine: r,
-		Actions: newActions,
+}
+
+// RemoveAction removes the Action at the given index from the Block, returning true if an Action was removed.
+// If the Action removed is before the currently running Action, the Block's index is shifted so that the same Action
+// continues running; if the currently running Action is removed, the Action that takes its place is initialized and
+// runs instead.
+func (b *Block) RemoveAction(index int) bool {
+
+	b.adoptActions()
+
+	if index < 0 || index >= len(b.actions) {
+		return false
 	}
 
-	for i, b := range r.Blocks {
-		if b.ID == id {
-			r.Blocks[i] = nil
-			r.Blocks = append(r.Blocks[:i], r.Blocks[i+1:]...)
+	b.actions = append(b.actions[:index], b.actions[index+1:]...)
+	b.Actions = b.actions
+	b.labels = nil
+
+	if len(b.actions) == 0 {
+		b.index = 0
+	} else if index < b.index {
+		b.index--
+	} else if index == b.index {
+		if b.index >= len(b.actions) {
+			b.index = len(b.actions) - 1
+		}
+		b.endCurrent()
+		b.needsInit = true
+		b.resetFrame()
+		if b.currentlyActive {
+			b.indexChanged = true
 		}
 	}
 
-	r.Blocks = append(r.Blocks, newBlock)
-	return newBlock
+	return true
+
 }
 
-// Properties returns the Properties object for the Routine.
-func (r *Routine) Properties() *Properties {
-	return r.properties
+// ForEachAction calls the given function for each Action in the Block, in order, along with its index.
+// If the function returns false, iteration stops. The Actions are iterated over using a copy of the Block's
+// Action list, so modifying the Block during iteration is safe.
+func (b *Block) ForEachAction(forEach func(i int, action Action) bool) {
+	for i, action := range append([]Action{}, b.actions...) {
+		if !forEach(i, action) {
+			return
+		}
+	}
 }
 
-// Update updates the Routine - this should be called once per frame.
-func (r *Routine) Update() {
+// Index returns the index of the currently active Action in the Block.
+func (b *Block) Index() int {
+	return b.index
+}
 
-	for _, block := range r.Blocks {
-		block.currentlyActive = block.active
+func (b *Block) update() {
+
+	if !b.currentlyActive {
+		b.updatedLast = false
+		return
 	}
 
-	for _, block := range r.Blocks {
-		block.update()
+	if len(b.actions) == 0 {
+		// An empty Block has nothing to do, so it finishes immediately.
+		if b.routine.strict {
+			b.routine.ReportError(fmt.Errorf("%w: block %v was run", ErrNoActions, b.ID))
+		}
+		b.active = false
+		b.currentlyActive = false
+		b.updatedLast = false
+		b.stopped = true
+		return
 	}
 
-}
+	report := &b.routine.report
 
-// Run runs Blocks with the given IDs.
-// If no block IDs are given, then all blocks contained in the Routine are run.
-func (r *Routine) Run(blockIDs ...any) {
-	if len(blockIDs) == 0 {
-		for _, block := range r.Blocks {
-			block.Run()
-		}
-	} else {
+	if b.awaitingPre {
 
-		for _, label := range blockIDs {
-			for _, block := range r.Blocks {
-				if block.ID == label {
-					block.Run()
-					break
-				}
+		// The Block has had its turn this frame even if it keeps waiting, so that it isn't updated again as pending
+		// under ActivateImmediately.
+		b.updatedThisFrame = true
+
+		if !b.precondition() {
+
+			b.preElapsed += b.routine.delta
+
+			if b.preTimeout > 0 && b.preElapsed >= b.preTimeout {
+				b.awaitingPre = false
+				b.err = fmt.Errorf("%w: block %v waited %s", ErrPreconditionTimeout, b.ID, b.preTimeout)
+				b.routine.ReportError(fmt.Errorf("routine: block %v failed: %w", b.ID, b.err))
+				report.Failed = append(report.Failed, b.ID)
+				report.Finished = append(report.Finished, b.ID)
+				b.active = false
+				b.currentlyActive = false
+				b.stopped = true
 			}
+
+			return
+
 		}
 
+		b.awaitingPre = false
+
 	}
-}
 
-// Pause pauses Blocks with the given IDs.
-// If no block IDs are given, then all blocks contained in the Routine are paused.
-func (r *Routine) Pause(blockIDs ...any) {
-	if len(blockIDs) == 0 {
-		for _, block := range r.Blocks {
-			block.Pause()
-		}
-	} else {
+	if !b.updatedLast {
+		report.Started = append(report.Started, b.ID)
+	}
 
-		for _, label := range blockIDs {
-			for _, block := range r.Blocks {
-				if block.ID == label {
-					block.Pause()
-					break
-				}
+	b.updatedLast = true
+	b.updatedThisFrame = true
+	b.advanced = false
+	b.actionElapsed += b.routine.delta
+	b.activeElapsed += b.routine.delta
+	b.runTime += b.routine.delta
+	b.runFrames++
+
+	if b.source != "" {
+		defer func() {
+			if err := recover(); err != nil {
+				panic(fmt.Sprintf("routine: panic in Action %d (%s) of Block %v (defined at %s): %v", b.index, describeAction(b.Action(b.index)), b.ID, b.source, err))
 			}
-		}
+		}()
+	}
+
+	if !b.held {
+		b.poll()
+	}
 
+	if b.advanced {
+		report.Advanced = append(report.Advanced, b.ID)
+	}
+
+	if !b.currentlyActive {
+		report.Finished = append(report.Finished, b.ID)
+		b.updatedLast = false
+		b.stopped = true
 	}
 
 }
 
-// Stop stops Blocks with the given IDs.
-// If no block IDs are given, then all blocks contained in the Routine are stopped.
-func (r *Routine) Stop(blockIDs ...any) {
-	if len(blockIDs) == 0 {
-		for _, block := range r.Blocks {
-			block.Stop()
-		}
-	} else {
+func (b *Block) poll() {
 
-		for _, label := range blockIDs {
-			for _, block := range r.Blocks {
-				if block.ID == label {
-					block.Stop()
-					break
-				}
-			}
+	b.indexChanged = false
+
+	index := b.index
+	action := b.actions[b.index]
+
+	// Actions are initialized lazily, right before they're first polled, so that they're initialized exactly once each
+	// time the Block arrives at them (and not when it merely stops or restarts).
+	if b.needsInit {
+		b.needsInit = false
+		action.Init(b)
+		b.entered = action
+	}
+
+	p := action.Poll(b)
+
+	if label, ok := action.(ActionIdentifiable); ok {
+		if b.labelCrossings == nil {
+			b.labelCrossings = map[any]labelCrossing{}
 		}
+		b.labelCrossings[label.ID()] = labelCrossing{tick: b.routine.ticks, elapsed: b.routine.elapsed}
 	}
 
-}
+	if p != FlowSkip {
+		b.routine.report.Polled++
+	}
 
-// Restart restarts Blocks with the given IDs.
-// If no block IDs are given, then all blocks contained in the Routine are restarted.
-func (r *Routine) Restart(blockIDs ...any) {
-	if len(blockIDs) == 0 {
+	b.currentFrame++
 
-		for _, block := range r.Blocks {
-			block.Restart()
-		}
+	if p == FlowNext || p == FlowSkip || p == FlowRestartBlock || b.indexChanged {
+		b.advanced = true
+	}
 
-	} else {
+	if b.finishing && p != FlowIdle && p != FlowFail {
+		p = FlowFinish
+	}
 
-		for _, label := range blockIDs {
-			for _, block := range r.Blocks {
-				if block.ID == label {
-					block.Restart()
-					break
-				}
-			}
-		}
+	if b.routine.onPoll != nil {
+		b.routine.onPoll(b, action, p)
+	}
 
+	if p != FlowIdle || b.indexChanged {
+		b.routine.history.record(Transition{Tick: b.routine.ticks, Block: b.ID, Index: index, Action: action, Flow: p})
 	}
-}
 
-// Running returns true if at least one Block is running with at least one of the given IDs in the Routine.
-// If no IDs are given, then any running Blocks will return.
-func (r *Routine) Running(ids ...any) bool {
-	if len(ids) == 0 {
-		for _, b := range r.Blocks {
-			if b.Running() {
-				return true
-			}
+	switch p {
+	case FlowNext, FlowSkip:
+
+		if !b.indexChanged {
+			b.index++
 		}
-	} else {
 
-		for _, id := range ids {
-			for _, b := range r.Blocks {
-				if b.ID == id && b.Running() {
-					return true
-				}
+		looped := false
+
+		if b.index > len(b.actions)-1 {
+			switch b.endBehavior {
+			case EndLoop:
+				b.index = 0
+				looped = true
+			case EndHold:
+				b.index = len(b.actions) - 1
+				b.held = true
+				b.endCurrent()
+				return
+			default:
+				b.index = 0
+				b.active = false
+				b.currentlyActive = false
 			}
 		}
 
-	}
-	return false
+		b.endCurrent()
+		b.needsInit = true
+		b.resetFrame()
+
+		// A looping Block continues on the following frame, so that a Block of instant Actions can't loop forever.
+		if b.active && !looped {
+			b.poll() // We call poll again because it should move on unless it's idling, specifically
+		}
+
+	case FlowFinish:
+		b.finishing = false
+		b.index = 0
+		b.active = false // Restart if we're going to the next Action and we're at the end of the block
+		b.currentlyActive = false
+		b.endCurrent()
+		b.needsInit = true
+		b.resetFrame()
+
+	case FlowFail:
+		if b.err == nil {
+			b.err = fmt.Errorf("%w: action %d (%s)", ErrBlockFailed, index, describeAction(action))
+		}
+		b.routine.ReportError(fmt.Errorf("routine: block %v failed: %w", b.ID, b.err))
+		b.routine.report.Failed = append(b.routine.report.Failed, b.ID)
+		b.finishing = false
+		b.index = 0
+		b.active = false
+		b.currentlyActive = false
+		b.endCurrent()
+		b.needsInit = true
+		b.resetFrame()
+
+	case FlowRestartBlock:
+		// Like a looping Block, a restarted Block continues on the following frame, so that a Block of instant Actions
+		// can't restart forever.
+		b.reset()
+		b.index = 0
+		b.endCurrent()
+		b.needsInit = true
+		b.resetFrame()
+
+	case FlowIdle:
+
+		// The Action changed the Block's index, which already set up the new Action; polling it again counted a frame.
+		if b.indexChanged {
+			b.resetFrame()
+		}
+
+	}
+
+}
+
+// SetEndBehavior sets what the Block does when it moves on from its last Action (see EndBehavior). By default, this is
+// EndStop. SetEndBehavior returns the Block for chaining.
+func (b *Block) SetEndBehavior(behavior EndBehavior) *Block {
+	b.endBehavior = behavior
+	return b
+}
+
+// EndBehavior returns what the Block does when it moves on from its last Action.
+func (b *Block) EndBehavior() EndBehavior {
+	return b.endBehavior
+}
+
+// SetAutoRemove sets whether the Block is removed from its Routine automatically once it stops (i.e. finishes, fails,
+// or is stopped) after running, keeping the Routine's memory bounded in games that define Blocks dynamically (e.g. per
+// spawned entity or event). Blocks are removed at the end of the Update() call in which they stop, or when
+// Routine.Prune() is called. Blocks created through Routine.RunTransient() are set to be removed automatically.
+// SetAutoRemove returns the Block for chaining.
+func (b *Block) SetAutoRemove(autoRemove bool) *Block {
+	b.autoRemove = autoRemove
+	return b
+}
+
+// AutoRemove returns if the Block is removed from its Routine automatically once it stops (see Block.SetAutoRemove()).
+func (b *Block) AutoRemove() bool {
+	return b.autoRemove
+}
+
+// Held returns if the Block is holding at its end, having moved on from its last Action with an EndBehavior of EndHold.
+// A held Block is still running, but doesn't poll its Actions.
+func (b *Block) Held() bool {
+	return b.held
+}
+
+// Continue pushes a held Block (see Block.Held()) past its hold. If Actions have been added to the Block since it
+// started holding (e.g. the next lines of a dialogue), the Block moves on to them; otherwise, it stops. This can be
+// used for dialogue Blocks that wait at their final line until the UI dismisses them, for example.
+// If the Block isn't held, Continue does nothing.
+func (b *Block) Continue() {
+
+	if !b.held {
+		return
+	}
+
+	if b.index+1 < len(b.actions) {
+		b.SetIndex(b.index + 1)
+	} else {
+		b.Stop()
+	}
+
+}
+
+// Run runs the specified block. If the Block has a precondition (see Block.SetPrecondition()) and is starting from its
+// beginning, it waits for the precondition to pass before actually starting.
+func (b *Block) Run() {
+	b.stopped = false
+	if b.err != nil {
+		b.err = nil
+		b.Restart()
+	}
+	if b.precondition != nil && !b.active && b.index == 0 && b.currentFrame == 0 {
+		b.awaitingPre = true
+		b.preElapsed = 0
+	}
+	b.active = true
+}
+
+// SetPrecondition sets a condition that the Block waits for when it's run from its beginning (see Block.Run()). Until
+// the condition returns true, the Block is running, but doesn't poll (or spend time on) its first Action - this is
+// useful to, for example, start a scene only once the area has finished streaming in. The condition is checked once
+// per Update, starting with the first Update after the Block is run. A nil condition removes the precondition.
+// It returns the Block for chaining.
+func (b *Block) SetPrecondition(condition func() bool) *Block {
+	b.precondition = condition
+	if condition == nil {
+		b.awaitingPre = false
+	}
+	return b
+}
+
+// SetPreconditionTimeout sets how long the Block waits for its precondition (see Block.SetPrecondition()) before
+// giving up and failing with ErrPreconditionTimeout (see Block.Failed()). The wait follows the Routine's time. A
+// timeout of 0 (the default) waits indefinitely. It returns the Block for chaining.
+func (b *Block) SetPreconditionTimeout(timeout time.Duration) *Block {
+	b.preTimeout = timeout
+	return b
+}
+
+// AwaitingPrecondition returns if the Block is running, but waiting for its precondition to pass before starting (see
+// Block.SetPrecondition()).
+func (b *Block) AwaitingPrecondition() bool {
+	return b.active && b.awaitingPre
+}
+
+// Fail sets the error the Block failed with, and returns FlowFail, so that Actions can fail their Block with an error
+// by returning the result (e.g. "return block.Fail(err)"). The Block then stops, and is marked as failed (see
+// Block.Failed()); the error is also reported to the Routine's error handler.
+func (b *Block) Fail(err error) Flow {
+	if err == nil {
+		err = ErrBlockFailed
+	}
+	b.err = err
+	return FlowFail
+}
+
+// Err returns the error the Block failed with (see Block.Fail()), or nil if it hasn't failed since it was last run or
+// restarted.
+func (b *Block) Err() error {
+	return b.err
+}
+
+// Failed returns if the Block failed (i.e. stopped because an Action returned FlowFail), as opposed to merely stopping.
+// A failed Block remains failed until it's run or restarted again.
+func (b *Block) Failed() bool {
+	return b.err != nil
+}
+
+// RunWith restarts the Block and runs it with the given arguments, which Actions can then read using Block.Args()
+// (see also actions.NewFunctionWithArgs()). This allows a single Block to be reused with different parameters (e.g. a
+// "greet" Block run with the name of the character to greet). The arguments persist until the Block is run with
+// different ones.
+func (b *Block) RunWith(args any) {
+	b.args = args
+	b.Restart()
+	b.Run()
+}
+
+// Args returns the arguments the Block was last run with using Block.RunWith(), or nil if it hasn't been run with any.
+func (b *Block) Args() any {
+	return b.args
+}
+
+// Running returns if the Block is active.
+func (b *Block) Running() bool {
+	return b.active
+}
+
+// Pause pauses the specified block, so that it isn't active when the Routine is run. When it is run again, it resumes execution at its current action.
+func (b *Block) Pause() {
+	b.active = false
+}
+
+// Restart restarts the block, clearing its result (see Block.SetResult()).
+func (b *Block) Restart() {
+	if b.index >= 0 && b.index < len(b.actions) {
+		if c, ok := b.actions[b.index].(actionCancelable); ok {
+			c.cancel()
+		}
+	}
+	b.result = nil
+	b.err = nil
+	b.index = -1
+	b.SetIndex(0)
+}
+
+// Stop stops the Block immediately, aborting the current Action, so that it restarts when it is run again.
+func (b *Block) Stop() {
+	b.finishing = false
+	b.awaitingPre = false
+	b.Pause()
+	b.Restart()
+	b.stopped = true
+}
+
+// Finish gracefully stops the Block. Rather than aborting immediately like Stop(), the Block continues to run until its
+// currently running Action completes (returning FlowNext or FlowFinish), at which point it stops, restarting when it is
+// run again. If the Block isn't running (or is held; see Block.Held()), then Finish simply stops it.
+func (b *Block) Finish() {
+	if !b.active || b.held {
+		b.Stop()
+		return
+	}
+	b.finishing = true
+}
+
+// StopRequested returns if the Block has been asked to finish gracefully (see Block.Finish()), but hasn't stopped yet.
+// Long-running custom Actions can check this to wrap up quickly (e.g. a typewriter Action could reveal the rest of its
+// text instantly) so that the Block deactivates sooner.
+func (b *Block) StopRequested() bool {
+	return b.finishing
+}
+
+// Properties returns the Block's local Properties, which can be used as memory that is local to the Block. The
+// Block's local Properties are cleared when it restarts through FlowRestartBlock (see actions.NewRestart()).
+func (b *Block) Properties() *Properties {
+	if b.properties == nil {
+		b.properties = &Properties{}
+	}
+	return b.properties
+}
+
+// reset resets the Block's local state (its local Properties, result, Label crossings, and timers).
+func (b *Block) reset() {
+	b.held = false
+	if b.properties != nil {
+		b.properties.Clear()
+	}
+	b.result = nil
+	b.labelCrossings = nil
+	b.activeElapsed = 0
+}
+
+// SetResult sets the Block's result to the given value. This allows Actions to pass values to following Actions
+// (which read it using Block.LastResult()) without having to use a property for each handoff - for example, one Action
+// could compute a path and set it as the result, and the next Action could then follow it.
+func (b *Block) SetResult(value any) {
+	b.result = value
+}
+
+// LastResult returns the value last set using Block.SetResult(), or nil if no result has been set since the Block
+// was last restarted.
+func (b *Block) LastResult() any {
+	return b.result
+}
+
+// Routine returns the currently running routine.
+func (b *Block) Routine() *Routine {
+	return b.routine
+}
+
+// Source returns the file:line location of the Routine.Define() call that created the Block and its Actions. This is
+// only captured if Routine.SetCaptureSource(true) was called before the Block was defined; otherwise, Source returns an
+// empty string.
+func (b *Block) Source() string {
+	return b.source
+}
+
+// endCurrent ends the Action the Block last initialized, if it hasn't been ended already (see ActionEndable).
+func (b *Block) endCurrent() {
+	action := b.entered
+	if action == nil {
+		return
+	}
+	b.entered = nil
+	if endable, ok := action.(ActionEndable); ok {
+		endable.End(b)
+	}
+}
+
+func (b *Block) resetFrame() {
+	b.currentFrame = 0
+	b.actionElapsed = 0
+}
+
+// CurrentActionElapsed returns how much time the Block has spent on the currently executed Action.
+// This increases every Routine.Update() call by the time elapsed since the previous Update, but only while the Block
+// is being updated, until the Block executes another Action.
+func (b *Block) CurrentActionElapsed() time.Duration {
+	return b.actionElapsed
+}
+
+// ActiveElapsed returns the total time the Block has spent running (i.e. being updated) since it was defined or since
+// Block.ResetActiveElapsed() was last called. This can be used to, for example, cut a scene short if it has been running
+// for too long.
+func (b *Block) ActiveElapsed() time.Duration {
+	return b.activeElapsed
+}
+
+// ResetActiveElapsed resets the Block's total running time (see Block.ActiveElapsed()) to zero.
+func (b *Block) ResetActiveElapsed() {
+	b.activeElapsed = 0
+}
+
+// CurrentFrame returns the current frame of the Block's execution of the currently executed Action.
+// This increases by 1 every Routine.Update() call until the Block executes another Action.
+func (b *Block) CurrentFrame() int {
+	return b.currentFrame
+}
+
+// RunningTime returns the total time the Block has been updated for. Unlike Block.ActiveElapsed(), this is never reset,
+// so Actions can measure how long they've been running by noting it when they're initialized. Unlike
+// Block.CurrentActionElapsed(), this also works for Actions nested in other Actions (like the Actions in a Gate), which
+// start and finish while the Block's current Action stays the same.
+func (b *Block) RunningTime() time.Duration {
+	return b.runTime
+}
+
+// RunningFrames returns the number of Routine.Update() calls the Block has been updated in. Like Block.RunningTime(),
+// this is never reset, so Actions can count the frames they've been running for by noting it when they're initialized.
+func (b *Block) RunningFrames() int {
+	return b.runFrames
+}
+
+// SetActionState stores the given state for the given Action in the Block, replacing any state previously stored for
+// it. This allows an Action that's shared between Blocks to keep separate state for each Block (e.g. when it started),
+// which lives only as long as the Block does. Setting the state to nil removes it.
+func (b *Block) SetActionState(action Action, state any) {
+	if state == nil {
+		delete(b.actionState, action)
+		return
+	}
+	if b.actionState == nil {
+		b.actionState = map[Action]any{}
+	}
+	b.actionState[action] = state
+}
+
+// ActionState returns the state stored for the given Action in the Block (see Block.SetActionState()), or nil if there
+// is none.
+func (b *Block) ActionState(action Action) any {
+	return b.actionState[action]
+}
+
+// DefinePolicy is simply a uint8, and represents what a Routine should do when a Block is defined using an ID that
+// is already in use.
+type DefinePolicy uint8
+
+const (
+	// ReplaceExisting means that the previously defined Block is removed and replaced by the new one. This is the default.
+	ReplaceExisting DefinePolicy = iota
+	// ErrorOnDuplicate means that the previously defined Block is kept, Define() returns nil, and ErrDuplicateID is
+	// reported to the Routine's error handler.
+	ErrorOnDuplicate
+	// PanicOnDuplicate means that Define() panics with ErrDuplicateID.
+	PanicOnDuplicate
+)
+
+// ActivationPolicy is simply a uint8, and represents when a Block that is run during a Routine.Update() call (e.g. by
+// an Action in another Block) is first updated.
+type ActivationPolicy uint8
+
+const (
+	// ActivateNextFrame means that Blocks run during an Update are first updated in the following Update, regardless
+	// of the order in which they were defined. Blocks that are paused or stopped during an Update aren't updated
+	// afterwards in that same Update. This is the default.
+	ActivateNextFrame ActivationPolicy = iota
+	// ActivateImmediately means that Blocks run during an Update are also updated in that same Update, regardless of
+	// the order in which they were defined. Each Block is still updated at most once per Update.
+	ActivateImmediately
+)
+
+// MutationPolicy is simply a uint8, and represents what happens when a Routine's structure or Blocks' states are
+// changed through the Routine (e.g. by calling Routine.Define() or Routine.Stop()) while it's updating Blocks.
+type MutationPolicy uint8
+
+const (
+	// MutateImmediately means that changes made during an Update are applied immediately, which can affect the rest of
+	// that Update (e.g. a Block defined by an Action isn't updated until the next Update, while a Block that's removed
+	// may cause the next Block in order to be skipped). This is the default.
+	MutateImmediately MutationPolicy = iota
+	// DeferMutations means that changes made through the Routine during an Update are queued, and applied in the order
+	// they were made once every Block has been updated (before the post-update function is called; see
+	// Routine.SetPostUpdate()). This applies to defining Blocks (including through RunTransient() and
+	// DefineCoroutine()), removing Blocks, and running, pausing, stopping, finishing, or restarting Blocks through the
+	// Routine; calling those functions on a Block directly still takes effect immediately.
+	DeferMutations
+)
+
+// Routine represents a container to run Blocks of code.
+type Routine struct {
+	// Deprecated: Blocks mirrors the Routine's list of Blocks for code written against older versions, and will be
+	// removed in the next release; use Routine.BlockList(), Routine.Define() and Routine.RemoveBlock() instead. A list
+	// assigned to it is adopted by the Routine the next time it's updated.
+	Blocks []*Block
+
+	blocks        []*Block
+	properties    *Properties
+	report        UpdateReport
+	captureSource bool
+	lastUpdate    time.Time
+	delta         time.Duration
+	unscaledDelta time.Duration
+	timeScale     float64
+	elapsed       time.Duration
+	ticks         int
+	tickRate      int           // The number of ticks per second in deterministic tick mode, or 0 (see Routine.SetTickRate()).
+	tickCount     int64         // The number of ticks that have passed in deterministic tick mode.
+	deltaSupplied bool          // Whether the Routine is in delta mode (see Routine.UpdateWithDelta()).
+	suppliedDelta time.Duration // The delta supplied to the current UpdateWithDelta() call.
+	clock         Clock
+	strict        bool
+	paused        bool
+	definePolicy  DefinePolicy
+	errorHandler  func(err error)
+	stateMutex    sync.RWMutex
+	preUpdate     func()
+	postUpdate    func()
+	onAllIdle     func()
+	onPoll        func(block *Block, action Action, flow Flow)
+	history       history
+	blockLoader   func(id any) *Block
+	transients    uint64
+	seed          int64
+	rand          *rand.Rand
+	signals       map[any]int // Signals by ID, mapped to the tick after which they expire (or signalLatched).
+	semaphores    map[any]*semaphore
+	queues        map[any][]any
+	cooldowns     *Cooldowns
+
+	activationPolicy ActivationPolicy
+	mutationPolicy   MutationPolicy
+	updating         bool        // Whether the Routine is currently updating Blocks.
+	inUpdate         atomic.Bool // Whether an Update() call is in progress.
+	deferred         []func()    // Mutations deferred until the end of the current Update.
+
+	inboxMutex sync.Mutex
+	inbox      []any
+}
+
+// New creates a new Routine.
+func New() *Routine {
+	r := &Routine{
+		blocks:     []*Block{},
+		properties: &Properties{},
+		timeScale:  1,
+	}
+	r.SetHistorySize(DefaultHistorySize)
+	return r
+}
+
+// Define defines a Block using the ID given and the list of Actions provided and adds it to the Routine.
+// The ID can be of any comparable type.
+// Define returns the new Block as well.
+// If a block with the given blockID already exists, what happens depends on the Routine's DefinePolicy
+// (see Routine.SetDefinePolicy()); by default, Define will remove the previous one.
+func (r *Routine) Define(id any, Actions ...Action) *Block {
+
+	if !r.allowDefine(id) {
+		return nil
+	}
+
+	newActions := r.validActions(id, FlattenActions(Actions...))
+
+	if r.strict && len(newActions) == 0 {
+		r.ReportError(fmt.Errorf("%w: block %v", ErrNoActions, id))
+	}
+
+	return r.define(id, newActions)
+}
+
+// DefineE is a variant of Define() that validates its arguments and returns an error rather than panicking or reporting
+// to the Routine's error handler, making it suitable for tools or pipelines that build Routines from untrusted data.
+// DefineE returns an error if the ID isn't comparable (ErrInvalidID), if no Actions are given (ErrNoActions), if any
+// given Action is nil (ErrNilAction), or if a Block with the given ID already exists and the Routine's DefinePolicy
+// isn't ReplaceExisting (ErrDuplicateID).
+func (r *Routine) DefineE(id any, Actions ...Action) (*Block, error) {
+
+	if id != nil && !reflect.TypeOf(id).Comparable() {
+		return nil, fmt.Errorf("%w: %T is not comparable", ErrInvalidID, id)
+	}
+
+	newActions := FlattenActions(Actions...)
+
+	if len(newActions) == 0 {
+		return nil, fmt.Errorf("%w: block %v", ErrNoActions, id)
+	}
+
+	for i, action := range newActions {
+		if action == nil {
+			return nil, fmt.Errorf("%w: block %v, action %d", ErrNilAction, id, i)
+		}
+	}
+
+	if r.definePolicy != ReplaceExisting && r.BlockByID(id) != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDuplicateID, id)
+	}
+
+	return r.define(id, newActions), nil
+
+}
+
+// validActions removes any nil Actions from the given slice, reporting an ErrNilAction in strict mode.
+func (r *Routine) validActions(id any, actions []Action) []Action {
+
+	valid := actions[:0]
+
+	for i, action := range actions {
+		if action == nil {
+			if r.strict {
+				r.ReportError(fmt.Errorf("%w: block %v, action %d", ErrNilAction, id, i))
+			}
+			continue
+		}
+		valid = append(valid, action)
+	}
+
+	return valid
+
+}
+
+// FlattenActions returns a new slice with any ActionCollectionables in the given Actions replaced with the Actions they
+// contain, recursively, in order. This is how Blocks, Gates, and Collections flatten the Actions they're given; custom
+// composites should use it (both for the Actions they're created with, and for the Actions they return from Actions())
+// so that they flatten consistently. Because flattened Actions take the place of their ActionCollectionable, any Labels
+// (or other ActionIdentifiables) among them are scoped to wherever the ActionCollectionable is added - a Block's Labels
+// can be jumped to from anywhere in the Block, while Labels in composites that run their Actions as a single Action
+// (like actions.NestedCollection) stay within that composite.
+func FlattenActions(actions ...Action) []Action {
+
+	newActions := []Action{}
+
+	for _, c := range actions {
+		if collection, ok := c.(ActionCollectionable); ok {
+			newActions = append(newActions, FlattenActions(collection.Actions()...)...)
+		} else {
+			newActions = append(newActions, c)
+		}
+	}
+
+	return newActions
+
+}
+
+// allowDefine applies the Routine's DefinePolicy, returning false if a Block with the given ID mustn't be defined.
+func (r *Routine) allowDefine(id any) bool {
+	if r.definePolicy != ReplaceExisting && r.BlockByID(id) != nil {
+		err := fmt.Errorf("%w: %v", ErrDuplicateID, id)
+		if r.definePolicy == PanicOnDuplicate {
+			panic(err)
+		}
+		r.ReportError(err)
+		return false
+	}
+	return true
+}
+
+// define creates the Block and adds it to the Routine; it should only be called directly from Define() or DefineE(),
+// as it captures the location of their caller.
+func (r *Routine) define(id any, actions []Action) *Block {
+
+	newBlock := &Block{
+		ID:        id,
+		Actions:   actions,
+		routine:   r,
+		actions:   actions,
+		needsInit: true,
+	}
+
+	if r.captureSource {
+		if _, file, line, ok := runtime.Caller(2); ok {
+			newBlock.source = fmt.Sprintf("%s:%d", file, line)
+		}
+	}
+
+	r.mutate(func() {
+		r.RemoveBlock(id)
+		r.blocks = append(r.blocks, newBlock)
+		r.Blocks = r.blocks
+	})
+
+	return newBlock
+}
+
+// SetActivationPolicy sets when Blocks that are run during a Routine.Update() call are first updated.
+// By default, this is ActivateNextFrame.
+func (r *Routine) SetActivationPolicy(policy ActivationPolicy) {
+	r.activationPolicy = policy
+}
+
+// ActivationPolicy returns the Routine's ActivationPolicy.
+func (r *Routine) ActivationPolicy() ActivationPolicy {
+	return r.activationPolicy
+}
+
+// SetMutationPolicy sets what happens when the Routine is changed while it's updating Blocks (see MutationPolicy).
+// By default, this is MutateImmediately.
+func (r *Routine) SetMutationPolicy(policy MutationPolicy) {
+	r.mutationPolicy = policy
+}
+
+// MutationPolicy returns the Routine's MutationPolicy.
+func (r *Routine) MutationPolicy() MutationPolicy {
+	return r.mutationPolicy
+}
+
+// mutate applies the given change to the Routine, or defers it until the end of the current Update if the Routine is
+// updating and its MutationPolicy is DeferMutations.
+func (r *Routine) mutate(change func()) {
+	if r.updating && r.mutationPolicy == DeferMutations {
+		r.deferred = append(r.deferred, change)
+		return
+	}
+	change()
+}
+
+// applyDeferred applies the changes deferred during the current Update.
+func (r *Routine) applyDeferred() {
+	for len(r.deferred) > 0 {
+		change := r.deferred[0]
+		r.deferred = r.deferred[1:]
+		change()
+	}
+	r.deferred = nil
+}
+
+// SetStrict sets whether the Routine is in strict mode. Normally, the Routine handles questionable usage gracefully:
+// nil Actions passed to Define() (or the Block functions that add Actions) are skipped, and empty Blocks simply finish
+// immediately when run. In strict mode, the Routine still does this, but also reports an error (ErrNilAction or
+// ErrNoActions) to its error handler (see Routine.SetErrorHandler()), making such mistakes easier to track down.
+func (r *Routine) SetStrict(strict bool) {
+	r.strict = strict
+}
+
+// Strict returns if the Routine is in strict mode.
+func (r *Routine) Strict() bool {
+	return r.strict
+}
+
+// SetDefinePolicy sets what the Routine does when Define() is called with a Block ID that is already in use.
+// By default, this is ReplaceExisting.
+func (r *Routine) SetDefinePolicy(policy DefinePolicy) {
+	r.definePolicy = policy
+}
+
+// DefinePolicy returns the Routine's DefinePolicy.
+func (r *Routine) DefinePolicy() DefinePolicy {
+	return r.definePolicy
+}
+
+// SetCaptureSource sets whether the Routine should capture the file:line location of each Define() call, making it
+// available through Block.Source() and adding it to the messages of panics raised by that Block's Actions.
+// This is disabled by default, as capturing the caller's location has a (small) cost. To capture the location each
+// individual Action was created at instead, see actions.SetCaptureSource().
+func (r *Routine) SetCaptureSource(capture bool) {
+	r.captureSource = capture
+}
+
+// Properties returns the Properties object for the Routine.
+func (r *Routine) Properties() *Properties {
+	return r.properties
+}
+
+// Update updates the Routine - this should be called once per frame.
+// Update mustn't be called while the Routine is already updating (e.g. from within an Action, or concurrently from
+// another goroutine); such calls do nothing, and report ErrReentrantUpdate to the Routine's error handler.
+func (r *Routine) Update() {
+	r.update(nil)
+}
+
+// UpdateOnly updates the Routine like Update(), but only updates the Blocks with the given IDs (which can be patterns;
+// see MatchID()). Other Blocks are left untouched, as though they were paused for this frame. This can be used to
+// advance UI-related Blocks while gameplay Blocks are frozen during a modal dialog, for example.
+func (r *Routine) UpdateOnly(blockIDs ...any) {
+	r.update(func(block *Block) bool { return matchesAny(blockIDs, block.ID) })
+}
+
+// UpdateExcept updates the Routine like Update(), but doesn't update the Blocks with the given IDs (which can be
+// patterns; see MatchID()). These Blocks are left untouched, as though they were paused for this frame.
+func (r *Routine) UpdateExcept(blockIDs ...any) {
+	r.update(func(block *Block) bool { return !matchesAny(blockIDs, block.ID) })
+}
+
+// UpdateWithDelta updates the Routine like Update(), but advances its time by the given delta (scaled by the Routine's
+// time scale) instead of the time measured since the previous Update. This is useful for games with a fixed simulation
+// timestep, so that the Routine follows game time rather than the wall clock (which keeps running while the game is
+// paused or its window is dragged, for example). Once UpdateWithDelta has been called, the Routine stays in delta mode:
+// Actions that would otherwise consult the Routine's Clock follow the Routine's time instead (see
+// Routine.WallClock()), and plain Update() calls advance its time by 0. Negative deltas are treated as 0. In
+// deterministic tick mode (see Routine.SetTickRate()), the delta is ignored.
+func (r *Routine) UpdateWithDelta(dt time.Duration) {
+	if dt < 0 {
+		dt = 0
+	}
+	r.deltaSupplied = true
+	r.suppliedDelta = dt
+	r.update(nil)
+}
+
+// WallClock returns if the Routine's time follows its Clock (the wall clock by default; see Routine.SetClock()) - that
+// is, if it's neither in deterministic tick mode (see Routine.SetTickRate()) nor in delta mode (see
+// Routine.UpdateWithDelta()). Custom Actions that measure time with the Routine's Clock should follow the Block's time
+// (see Block.CurrentActionElapsed()) instead when this is false.
+func (r *Routine) WallClock() bool {
+	return r.tickRate <= 0 && !r.deltaSupplied
+}
+
+func matchesAny(patterns []any, id any) bool {
+	for _, pattern := range patterns {
+		if MatchID(pattern, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// update updates the Routine, only updating the Blocks for which include returns true (or all Blocks if include is nil).
+func (r *Routine) update(include func(block *Block) bool) {
+
+	// This is checked before locking, as an Update() call from within an Action would otherwise deadlock.
+	if !r.inUpdate.CompareAndSwap(false, true) {
+		r.ReportError(ErrReentrantUpdate)
+		return
+	}
+	defer r.inUpdate.Store(false)
+
+	r.stateMutex.Lock()
+	defer r.stateMutex.Unlock()
+
+	r.adoptBlocks()
+
+	r.report.reset()
+
+	if r.paused {
+		return
+	}
+
+	if r.tickRate > 0 {
+		// Each tick's length is derived from the total tick count, so rounding never accumulates.
+		r.unscaledDelta = r.tickTime(r.tickCount+1) - r.tickTime(r.tickCount)
+		r.tickCount++
+		r.delta = r.unscaledDelta
+	} else {
+		if r.deltaSupplied {
+			r.unscaledDelta = r.suppliedDelta
+			r.suppliedDelta = 0
+		} else {
+			clock := r.Clock()
+			if r.lastUpdate.IsZero() {
+				r.unscaledDelta = 0
+			} else {
+				r.unscaledDelta = clock.Since(r.lastUpdate)
+			}
+			now := clock.Now()
+			r.lastUpdate = now
+		}
+		r.delta = time.Duration(float64(r.unscaledDelta) * r.timeScale)
+	}
+	r.elapsed += r.delta
+	r.ticks++
+
+	if r.preUpdate != nil {
+		r.preUpdate()
+	}
+
+	r.updating = true
+	defer func() { r.updating = false }()
+
+	blocks := r.blocks
+	if include != nil {
+		blocks = make([]*Block, 0, len(r.blocks))
+		for _, block := range r.blocks {
+			if include(block) {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+
+	for _, block := range blocks {
+		block.applySkip()
+		block.currentlyActive = block.active
+		block.updatedThisFrame = false
+	}
+
+	for _, block := range blocks {
+		if r.activationPolicy == ActivateImmediately {
+			block.currentlyActive = block.active
+		} else {
+			block.currentlyActive = block.currentlyActive && block.active
+		}
+		block.update()
+	}
+
+	if r.activationPolicy == ActivateImmediately {
+
+		// Blocks that were run by Blocks later in the update order haven't been updated yet; keep going
+		// until every running Block has been updated once.
+		for pending := true; pending; {
+			pending = false
+			for _, block := range blocks {
+				if block.active && !block.updatedThisFrame {
+					block.currentlyActive = true
+					block.update()
+					pending = true
+				}
+			}
+		}
+
+	}
+
+	r.updating = false
+	r.applyDeferred()
+
+	r.Prune()
+
+	if r.onAllIdle != nil && r.report.Polled > 0 && len(r.report.Advanced) == 0 && len(r.report.Finished) == 0 {
+		r.onAllIdle()
+	}
+
+	if r.postUpdate != nil {
+		r.postUpdate()
+	}
+
+}
+
+// SetPaused sets whether the Routine is paused. While paused, Update() does nothing - no Blocks are updated, and the
+// Routine's time (see Routine.Elapsed()) doesn't advance, freezing Actions that follow it. Unlike pausing Blocks
+// individually, this doesn't touch any Block's running state, so a global pause (e.g. a pause menu) can't be confused
+// with or clobbered by script-driven Pause() and Run() calls. The time spent paused isn't counted as delta time once
+// the Routine is unpaused.
+func (r *Routine) SetPaused(paused bool) {
+	if r.paused && !paused {
+		r.lastUpdate = time.Time{}
+	}
+	r.paused = paused
+}
+
+// Paused returns if the Routine is paused (see Routine.SetPaused()).
+func (r *Routine) Paused() bool {
+	return r.paused
+}
+
+// SetPreUpdate sets a function to be called at the start of each Routine.Update() call, before any Blocks are updated.
+// This can be used to synchronize external state (e.g. copying an input snapshot) at a well-defined point.
+func (r *Routine) SetPreUpdate(preUpdate func()) {
+	r.preUpdate = preUpdate
+}
+
+// SetPostUpdate sets a function to be called at the end of each Routine.Update() call, after all Blocks have been updated.
+func (r *Routine) SetPostUpdate(postUpdate func()) {
+	r.postUpdate = postUpdate
+}
+
+// SetOnAllIdle sets a function to be called at the end of each Routine.Update() call in which every updated Block
+// idled (i.e. returned FlowIdle without moving to another Action or finishing). This can be used to detect that the
+// Routine is waiting on something, to show a prompt like "press A to continue", for example. The function isn't called
+// when no Blocks are running.
+func (r *Routine) SetOnAllIdle(onAllIdle func()) {
+	r.onAllIdle = onAllIdle
+}
+
+// SetOnPoll sets a function to be called each time an Action is polled, with the Block it belongs to, the Action, and
+// the Flow that the Block will follow as a result. This can be used to trace the execution of a Routine for debugging
+// or testing (see the routinetest package).
+func (r *Routine) SetOnPoll(onPoll func(block *Block, action Action, flow Flow)) {
+	r.onPoll = onPoll
+}
+
+// SetTimeScale sets the Routine's time scale, which is multiplied against the time that passes between Update() calls.
+// This affects the Routine's time (see Routine.Elapsed(), Block.CurrentActionElapsed(), and Block.ActiveElapsed()),
+// and so any Actions that are driven by it; a time scale of 0.5 makes them progress at half speed, for example.
+// Negative time scales are treated as 0. By default, the time scale is 1. The time scale is ignored in deterministic
+// tick mode (see Routine.SetTickRate()).
+func (r *Routine) SetTimeScale(scale float64) {
+	if scale < 0 {
+		scale = 0
+	}
+	r.timeScale = scale
+}
+
+// TimeScale returns the Routine's time scale.
+func (r *Routine) TimeScale() float64 {
+	return r.timeScale
+}
+
+// DeltaTime returns the (scaled) time that passed between the last two Update() calls.
+func (r *Routine) DeltaTime() time.Duration {
+	return r.delta
+}
+
+// UnscaledDeltaTime returns the time that passed between the last two Update() calls, ignoring the Routine's time scale.
+func (r *Routine) UnscaledDeltaTime() time.Duration {
+	return r.unscaledDelta
+}
+
+// Elapsed returns the total time the Routine has been updated for since it was created or since
+// Routine.ResetElapsed() was last called.
+func (r *Routine) Elapsed() time.Duration {
+	return r.elapsed
+}
+
+// ResetElapsed resets the Routine's total elapsed time (see Routine.Elapsed()) to zero. Running cooldowns (see
+// Routine.Cooldowns()) keep the time they have remaining.
+func (r *Routine) ResetElapsed() {
+	if r.cooldowns != nil {
+		for name, readyAt := range r.cooldowns.readyAt {
+			r.cooldowns.readyAt[name] = readyAt - r.elapsed
+		}
+	}
+	r.elapsed = 0
+}
+
+// LastUpdateReport returns an UpdateReport detailing what happened in the last Routine.Update() call.
+// Note that the slices contained in the report are reused by the Routine, and so are only valid until
+// the next Update() call.
+func (r *Routine) LastUpdateReport() UpdateReport {
+	return r.report
+}
+
+// Run runs Blocks with the given IDs.
+// If no block IDs are given, then all blocks contained in the Routine are run.
+// IDs can also be patterns matching multiple Blocks (see MatchID()).
+func (r *Routine) Run(blockIDs ...any) {
+	r.mutate(func() {
+		r.loadBlocks(blockIDs)
+		r.forBlocks(blockIDs, (*Block).Run)
+	})
+}
+
+// RunWith restarts and runs Blocks with the given IDs with the given arguments (see Block.RunWith()).
+// If no block IDs are given, then all blocks contained in the Routine are run with the arguments.
+// IDs can also be patterns matching multiple Blocks (see MatchID()).
+func (r *Routine) RunWith(args any, blockIDs ...any) {
+	r.mutate(func() {
+		r.loadBlocks(blockIDs)
+		r.forBlocks(blockIDs, func(b *Block) { b.RunWith(args) })
+	})
+}
+
+// Pause pauses Blocks with the given IDs.
+// If no block IDs are given, then all blocks contained in the Routine are paused.
+// IDs can also be patterns matching multiple Blocks (see MatchID()).
+func (r *Routine) Pause(blockIDs ...any) {
+	r.mutate(func() {
+		r.forBlocks(blockIDs, (*Block).Pause)
+	})
+}
+
+// Stop stops Blocks with the given IDs immediately, aborting their currently running Actions.
+// If no block IDs are given, then all blocks contained in the Routine are stopped.
+// IDs can also be patterns matching multiple Blocks (see MatchID()).
+func (r *Routine) Stop(blockIDs ...any) {
+	r.mutate(func() {
+		r.forBlocks(blockIDs, (*Block).Stop)
+	})
+}
+
+// Finish gracefully stops Blocks with the given IDs, allowing each Block to complete its currently running Action
+// before stopping (see Block.Finish()).
+// If no block IDs are given, then all blocks contained in the Routine are finished.
+// IDs can also be patterns matching multiple Blocks (see MatchID()).
+func (r *Routine) Finish(blockIDs ...any) {
+	r.mutate(func() {
+		r.forBlocks(blockIDs, (*Block).Finish)
+	})
+}
+
+// Restart restarts Blocks with the given IDs.
+// If no block IDs are given, then all blocks contained in the Routine are restarted.
+// IDs can also be patterns matching multiple Blocks (see MatchID()).
+func (r *Routine) Restart(blockIDs ...any) {
+	r.mutate(func() {
+		r.forBlocks(blockIDs, (*Block).Restart)
+	})
+}
+
+// Running returns true if at least one Block is running with at least one of the given IDs in the Routine.
+// If no IDs are given, then any running Blocks will return.
+// IDs can also be patterns matching multiple Blocks (see MatchID()).
+// Failed Blocks aren't running; use Routine.Failed() to tell them apart from Blocks that stopped normally.
+func (r *Routine) Running(ids ...any) bool {
+	for _, b := range r.BlocksMatching(ids...) {
+		if b.Running() {
+			return true
+		}
+	}
+	return false
+}
+
+// Failed returns true if at least one Block with at least one of the given IDs in the Routine has failed (see
+// Block.Failed()). If no IDs are given, then any failed Blocks will return.
+// IDs can also be patterns matching multiple Blocks (see MatchID()).
+func (r *Routine) Failed(ids ...any) bool {
+	for _, b := range r.BlocksMatching(ids...) {
+		if b.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockList returns a copy of the list of Blocks in the Routine, in the order they were defined. To add or remove
+// Blocks, use Routine.Define() and Routine.RemoveBlock(), so that the Routine can keep its internal state consistent.
+// (This replaces the deprecated Routine.Blocks field.)
+func (r *Routine) BlockList() []*Block {
+	return append([]*Block{}, r.blocks...)
+}
+
+// adoptBlocks adopts a list of Blocks assigned to the deprecated Routine.Blocks field since the Routine's Blocks were
+// last modified, if any, along with any lists assigned to the deprecated Block.Actions fields.
+func (r *Routine) adoptBlocks() {
+	if !sameSlice(r.Blocks, r.blocks) {
+		r.blocks = append([]*Block{}, r.Blocks...)
+		r.Blocks = r.blocks
+	}
+	for _, b := range r.blocks {
+		b.adoptActions()
+	}
+}
+
+// RemoveBlock removes the Block with the given ID from the Routine, returning true if a Block was removed.
+// Removing a coroutine Block cancels its body (see Routine.DefineCoroutine()).
+func (r *Routine) RemoveBlock(id any) bool {
+	if r.updating && r.mutationPolicy == DeferMutations {
+		r.deferred = append(r.deferred, func() { r.removeBlock(id) })
+		return r.BlockByID(id) != nil
+	}
+	return r.removeBlock(id)
+}
+
+func (r *Routine) removeBlock(id any) bool {
+	for i, b := range r.blocks {
+		if b.ID == id {
+			b.endCurrent()
+			for _, action := range b.actions {
+				if c, ok := action.(actionCancelable); ok {
+					c.cancel()
+				}
+			}
+			r.blocks[i] = nil
+			r.blocks = append(r.blocks[:i], r.blocks[i+1:]...)
+			r.Blocks = r.blocks
+			return true
+		}
+	}
+	return false
+}
+
+// ForEachBlock calls the given function for each Block in the Routine, in the order they were defined.
+// If the function returns false, iteration stops. The Blocks are iterated over using a copy of the Routine's
+// Block list, so defining or removing Blocks during iteration is safe.
+func (r *Routine) ForEachBlock(forEach func(block *Block) bool) {
+	for _, block := range append([]*Block{}, r.blocks...) {
+		if !forEach(block) {
+			return
+		}
+	}
+}
+
+// BlocksMatching returns all Blocks that match at least one of the given IDs or ID patterns (see MatchID()).
+// If no IDs are given, then all Blocks contained in the Routine are returned.
+func (r *Routine) BlocksMatching(ids ...any) []*Block {
+	blocks := []*Block{}
+	r.forBlocks(ids, func(b *Block) { blocks = append(blocks, b) })
+	return blocks
+}
+
+// SetBlockLoader sets a function that is called to load Blocks lazily - when Blocks are run by ID (see Routine.Run())
+// and no Block matches one of the given IDs, the loader is called with that ID. The loader should then define the
+// Block (using Routine.Define()) and return it, or return nil if no Block with that ID exists. This allows data-driven
+// Routines with thousands of Blocks to only load the ones that are actually played, so that memory use and startup time
+// scale accordingly. Loaded Blocks stay in the Routine until they're removed (see Routine.RemoveBlock()).
+func (r *Routine) SetBlockLoader(loader func(id any) *Block) {
+	r.blockLoader = loader
+}
+
+// loadBlocks calls the Routine's block loader for each of the given IDs that doesn't match any Block.
+func (r *Routine) loadBlocks(ids []any) {
+
+	if r.blockLoader == nil {
+		return
+	}
+
+	for _, id := range ids {
+
+		found := false
+		for _, block := range r.blocks {
+			if MatchID(id, block.ID) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			r.blockLoader(id)
+		}
+
+	}
+
+}
+
+func (r *Routine) forBlocks(ids []any, forEach func(b *Block)) {
+
+	if len(ids) == 0 {
+		for _, block := range r.blocks {
+			forEach(block)
+		}
+		return
+	}
+
+	// Blocks are visited in the order their IDs were given (and, for patterns, in definition order), visiting each
+	// Block only once even if it matches multiple IDs.
+	var visited map[*Block]bool
+	if len(ids) > 1 {
+		visited = map[*Block]bool{}
+	}
+
+	for _, id := range ids {
+		for _, block := range r.blocks {
+			if !MatchID(id, block.ID) || visited[block] {
+				continue
+			}
+			if visited != nil {
+				visited[block] = true
+			}
+			forEach(block)
+		}
+	}
+
+}
+
+// NextWakeTime returns the earliest time at which a currently running Block needs to be updated, along with a boolean
+// indicating if such a time could be determined. This is only possible when every running Block is idling on an Action
+// that implements ActionWakeable (like actions.Wait); otherwise, or if no Blocks are running (or the Routine doesn't
+// follow the wall clock, see Routine.WallClock(); or its time scale is 0, see Routine.SetTimeScale()), NextWakeTime
+// returns false. As wake times are computed with the time scale at the time, the host should update the Routine (and
+// ask again) after changing its time scale.
+func (r *Routine) NextWakeTime() (time.Time, bool) {
+
+	// Unless the Routine follows the wall clock, its time only advances when it's updated, so no Update can be skipped.
+	// When it's frozen, nothing would wake it but a change in its time scale.
+	if !r.WallClock() || r.timeScale == 0 {
+		return time.Time{}, false
+	}
+
+	wakeTime := time.Time{}
+	found := false
+
+	for _, block := range r.blocks {
+
+		if !block.active || block.held {
+			continue
+		}
+
+		// Blocks waiting for their precondition must check it every Update, and Actions that haven't been initialized
+		// yet can't know when they'll finish.
+		if len(block.actions) == 0 || block.awaitingPre || block.needsInit {
+			return time.Time{}, false
+		}
+
+		wakeable, ok := block.actions[block.index].(ActionWakeable)
+		if !ok {
+			return time.Time{}, false
+		}
+
+		if t := wakeable.WakeTime(); !found || t.Before(wakeTime) {
+			wakeTime = t
+		}
+
+		found = true
+
+	}
+
+	return wakeTime, found
+
+}
+
+// CanSleepUntil returns if the host loop can sleep instead of calling Routine.Update() every frame, and if so, the time
+// at which it should wake up and update the Routine again. If no Blocks are running at all, CanSleepUntil returns a zero
+// time and true, indicating that no updates are necessary until a Block is run.
+func (r *Routine) CanSleepUntil() (time.Time, bool) {
+	if !r.Running() {
+		return time.Time{}, true
+	}
+	return r.NextWakeTime()
+}
+
+// TransientID is the type of the IDs of Blocks created by Routine.RunTransient().
+type TransientID uint64
+
+// RunTransient defines an anonymous Block with the given Actions and runs it, returning the Block. The Block is given
+// a unique TransientID, and removes itself from the Routine once it stops (at the end of the Update() call in which it
+// finishes, or of the next one if it's stopped outside of an Update), so that one-off sequences don't accumulate in the
+// Routine over a long play session.
+func (r *Routine) RunTransient(actions ...Action) *Block {
+	r.transients++
+	id := TransientID(r.transients)
+	block := r.define(id, r.validActions(id, FlattenActions(actions...)))
+	block.SetAutoRemove(true)
+	block.Run()
+	return block
+}
+
+// Prune removes Blocks that are set to be removed automatically (see Block.SetAutoRemove()) and have stopped from the
+// Routine, returning the number of Blocks removed. This is done automatically at the end of each Update() call, but can
+// be called manually to drop such Blocks immediately (e.g. after stopping them outside of an Update).
+func (r *Routine) Prune() int {
+	removed := 0
+	for i := len(r.blocks) - 1; i >= 0; i-- {
+		if block := r.blocks[i]; block.autoRemove && block.stopped && !block.active {
+			r.RemoveBlock(block.ID)
+			removed++
+		}
+	}
+	return removed
+}
+
+// JumpTo jumps the Block with the given ID to the Label with the given label ID (see Block.JumpTo()) and runs it,
+// allowing, for example, long dialogue trees split across multiple Blocks to jump into the middle of another Block.
+// If the Block isn't found, the Routine's block loader is called (see Routine.SetBlockLoader()). JumpTo returns the
+// index jumped to, or -1 if the Block or the Label couldn't be found (in which case the Block isn't run).
+func (r *Routine) JumpTo(blockID, labelID any) int {
+
+	r.loadBlocks([]any{blockID})
+
+	block := r.BlockByID(blockID)
+	if block == nil {
+		return -1
+	}
+
+	if block.LabelIndex(labelID) < 0 {
+		return -1
+	}
+
+	// Run first, as running a failed Block restarts it.
+	block.Run()
+	return block.JumpTo(labelID)
+
 }
 
 // BlockByID returns any Block found with the given ID.
 // If no Block with the given id is found, nil is returned.
 func (r *Routine) BlockByID(id any) *Block {
-	for _, block := range r.Blocks {
+	for _, block := range r.blocks {
 		if block.ID == id {
 			return block
 		}
@@ -0,0 +1,225 @@
+package routine_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/actions"
+)
+
+// updateWithin updates the Routine the given number of times, failing the test if the updates don't return in time
+// (e.g. because they loop forever).
+func updateWithin(t *testing.T, r *routine.Routine, updates int) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < updates; i++ {
+			r.Update()
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Routine.Update() didn't return")
+	}
+}
+
+func TestPreconditionActivateImmediately(t *testing.T) {
+
+	r := routine.New()
+	r.SetActivationPolicy(routine.ActivateImmediately)
+
+	ready := false
+	ran := false
+
+	r.Define("block", actions.NewFunction(func(block *routine.Block) routine.Flow {
+		ran = true
+		return routine.FlowIdle
+	})).SetPrecondition(func() bool { return ready })
+
+	r.Run("block")
+
+	updateWithin(t, r, 3)
+
+	if ran {
+		t.Fatalf("Block ran before its precondition passed")
+	}
+
+	ready = true
+	updateWithin(t, r, 1)
+
+	if !ran {
+		t.Fatalf("Block didn't run once its precondition passed")
+	}
+
+}
+
+func TestRestartInstantBlock(t *testing.T) {
+
+	r := routine.New()
+
+	count := 0
+
+	r.Define("block", actions.NewFunction(func(block *routine.Block) routine.Flow {
+		count++
+		return routine.FlowNext
+	}), actions.NewRestart())
+
+	r.Run("block")
+
+	updateWithin(t, r, 3)
+
+	if count != 3 {
+		t.Fatalf("Block restarted %d times in 3 updates, expected once per update", count)
+	}
+
+}
+
+func TestInsertActionsBeforeStart(t *testing.T) {
+
+	r := routine.New()
+
+	order := []string{}
+	record := func(name string) routine.Action {
+		return actions.NewFunction(func(block *routine.Block) routine.Flow {
+			order = append(order, name)
+			return routine.FlowNext
+		})
+	}
+
+	block := r.Define("block", record("b"))
+	block.InsertActions(0, record("a"))
+
+	r.Run("block")
+	r.Update()
+
+	if len(order) != 2 || order[0] != "a" || order[1] != "b" {
+		t.Fatalf("ran %v, expected [a b]", order)
+	}
+
+}
+
+// updatesUntil updates the Routine until done returns true, returning the number of updates it took (or -1 if it took
+// more than the given limit).
+func updatesUntil(r *routine.Routine, limit int, done func() bool) int {
+	for i := 1; i <= limit; i++ {
+		r.Update()
+		if done() {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRestoreStateResumesWait(t *testing.T) {
+
+	r := routine.New()
+	r.SetTickRate(10)
+
+	finished := false
+
+	r.Define("block", actions.NewWait(time.Second), actions.NewFunction(func(block *routine.Block) routine.Flow {
+		finished = true
+		return routine.FlowIdle
+	}))
+	r.Run("block")
+
+	for i := 0; i < 3; i++ {
+		r.Update()
+	}
+
+	buffer := &routine.StateBuffer{}
+	r.CopyStateTo(buffer)
+
+	expected := updatesUntil(r, 100, func() bool { return finished })
+	if expected < 0 {
+		t.Fatalf("Wait didn't finish")
+	}
+
+	r.RestoreStateFrom(buffer)
+	finished = false
+
+	if got := updatesUntil(r, 100, func() bool { return finished }); got != expected {
+		t.Fatalf("Wait finished %d updates after restoring, expected %d", got, expected)
+	}
+
+}
+
+func TestDeprecatedFields(t *testing.T) {
+
+	r := routine.New()
+
+	ran := []string{}
+	record := func(name string) routine.Action {
+		return actions.NewFunction(func(block *routine.Block) routine.Flow {
+			ran = append(ran, name)
+			return routine.FlowNext
+		})
+	}
+
+	block := r.Define("block", record("a"))
+
+	if len(r.Blocks) != 1 || r.Blocks[0] != block || len(block.Actions) != 1 {
+		t.Fatalf("deprecated fields don't mirror the Routine's Blocks and the Block's Actions")
+	}
+
+	block.Actions = append(block.Actions, record("b"))
+
+	block.Run()
+	r.Update()
+
+	if len(ran) != 2 || ran[1] != "b" {
+		t.Fatalf("ran %v, expected the Action appended to Block.Actions to run", ran)
+	}
+
+	block.AddActions(record("c"))
+	if len(block.Actions) != 3 {
+		t.Fatalf("Block.Actions has %d Actions after AddActions(), expected 3", len(block.Actions))
+	}
+
+	r.Blocks = r.Blocks[:0]
+	r.Update()
+	if len(r.BlockList()) != 0 {
+		t.Fatalf("Block removed from Routine.Blocks wasn't removed from the Routine")
+	}
+
+}
+
+func TestSemaphore(t *testing.T) {
+
+	r := routine.New()
+	r.SetSemaphore("door", 2)
+
+	errs := []error{}
+	r.SetErrorHandler(func(err error) { errs = append(errs, err) })
+
+	for _, n := range []int{0, -1} {
+		if acquired, err := r.TryAcquire("door", n); acquired || !errors.Is(err, routine.ErrSemaphore) {
+			t.Fatalf("TryAcquire(%d) returned %v, %v; expected an ErrSemaphore", n, acquired, err)
+		}
+	}
+
+	if acquired, err := r.TryAcquire("door", 2); !acquired || err != nil {
+		t.Fatalf("couldn't acquire the whole capacity: %v", err)
+	}
+	if acquired, _ := r.TryAcquire("door", 1); acquired {
+		t.Fatalf("acquired more than the capacity")
+	}
+
+	r.Release("door", -1)
+	if held, _, _ := r.Semaphore("door"); held != 2 {
+		t.Fatalf("releasing a negative amount changed the units held to %d", held)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], routine.ErrSemaphore) {
+		t.Fatalf("releasing a negative amount reported %v, expected an ErrSemaphore", errs)
+	}
+
+	r.Release("door", 1)
+	if acquired, _ := r.TryAcquire("door", 1); !acquired {
+		t.Fatalf("couldn't acquire a released unit")
+	}
+
+}
@@ -0,0 +1,167 @@
+// routinetest is a package that provides utilities for record-and-assert ("golden") tests of scripted behavior.
+// A Recorder updates a Routine for a number of frames, recording the ordered trace of polled Actions (their Block, name,
+// and resulting Flow); AssertGolden() then compares the trace against a golden file, so that content changes that alter
+// scripted behavior are caught in CI.
+//
+// Recorders put their Routine into deterministic tick mode (see Routine.SetTickRate()) at TickRate, and seed its random
+// source (see Routine.SetSeed()) with Seed, so that timed Actions (like actions.Wait) and Actions that use the random
+// source (like actions.NewWaitJitter()) behave the same from run to run. Note that Actions that use the global random
+// source can't be traced deterministically.
+package routinetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/solarlune/routine"
+)
+
+// UpdateEnv is the environment variable that, when set to a non-empty value, makes AssertGolden() write the recorded
+// trace to the golden file instead of comparing against it.
+const UpdateEnv = "ROUTINE_UPDATE_GOLDEN"
+
+// Seed is the seed Recorders use for their Routine's random source.
+const Seed = 1
+
+// TickRate is the number of ticks per second Recorders run their Routine at, so each recorded frame is 1/TickRate
+// seconds long.
+const TickRate = 60
+
+// Step represents a single polled Action in a trace.
+type Step struct {
+	Frame       int            // The frame (starting from 0) in which the Action was polled
+	Block       any            // The ID of the Block the Action belongs to
+	Action      string         // The name of the Action (see routine.ActionName())
+	Flow        routine.Flow   // The Flow the Block followed as a result of polling the Action
+	Annotations map[string]any // The Action's metadata, if any (see routine.ActionAnnotatable)
+}
+
+// String returns the Step as tab-separated text. If the Action has metadata, it's appended as a fifth column (see
+// routine.FormatAnnotations()).
+func (s Step) String() string {
+	if len(s.Annotations) > 0 {
+		return fmt.Sprintf("%d\t%v\t%s\t%s\t%s", s.Frame, s.Block, s.Action, s.Flow, routine.FormatAnnotations(s.Annotations))
+	}
+	return fmt.Sprintf("%d\t%v\t%s\t%s", s.Frame, s.Block, s.Action, s.Flow)
+}
+
+// Recorder records the trace of a Routine as it's updated.
+type Recorder struct {
+	routine *routine.Routine
+	frame   int
+	steps   []Step
+}
+
+// NewRecorder creates a new Recorder for the given Routine, putting it into deterministic tick mode at TickRate and
+// seeding its random source with Seed. Note that this replaces the Routine's OnPoll function (see Routine.SetOnPoll()).
+func NewRecorder(r *routine.Routine) *Recorder {
+
+	rec := &Recorder{routine: r}
+
+	r.SetTickRate(TickRate)
+	r.SetSeed(Seed)
+
+	r.SetOnPoll(func(block *routine.Block, action routine.Action, flow routine.Flow) {
+		rec.steps = append(rec.steps, Step{
+			Frame:       rec.frame,
+			Block:       block.ID,
+			Action:      routine.ActionName(action),
+			Flow:        flow,
+			Annotations: routine.ActionAnnotations(action),
+		})
+	})
+
+	return rec
+
+}
+
+// Run updates the Routine for the given number of frames, recording each polled Action.
+func (rec *Recorder) Run(frames int) {
+	for i := 0; i < frames; i++ {
+		rec.routine.Update()
+		rec.frame++
+	}
+}
+
+// RunUntilStopped updates the Routine until no Blocks are running, or until the given maximum number of frames has
+// passed. It returns true if the Routine stopped.
+func (rec *Recorder) RunUntilStopped(maxFrames int) bool {
+	for i := 0; i < maxFrames; i++ {
+		if !rec.routine.Running() {
+			return true
+		}
+		rec.routine.Update()
+		rec.frame++
+	}
+	return !rec.routine.Running()
+}
+
+// Trace returns a copy of the recorded trace.
+func (rec *Recorder) Trace() []Step {
+	return append([]Step{}, rec.steps...)
+}
+
+// Reset clears the recorded trace and resets the frame counter.
+func (rec *Recorder) Reset() {
+	rec.steps = nil
+	rec.frame = 0
+}
+
+// String returns the recorded trace as text, with one tab-separated Step per line.
+func (rec *Recorder) String() string {
+	var builder strings.Builder
+	for _, step := range rec.steps {
+		builder.WriteString(step.String())
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+// AssertGolden compares the Recorder's trace against the golden file at the given path, failing the test if they
+// differ or if the file doesn't exist. If the environment variable named by UpdateEnv is set, the trace is written to
+// the golden file instead.
+func AssertGolden(t testing.TB, rec *Recorder, path string) {
+
+	t.Helper()
+
+	trace := rec.String()
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("routinetest: creating golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(trace), 0644); err != nil {
+			t.Fatalf("routinetest: writing golden file: %v", err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("routinetest: reading golden file (set %s=1 to create it): %v", UpdateEnv, err)
+	}
+
+	if string(golden) == trace {
+		return
+	}
+
+	goldenLines := strings.Split(string(golden), "\n")
+	traceLines := strings.Split(trace, "\n")
+
+	for i := 0; i < len(goldenLines) || i < len(traceLines); i++ {
+		want, got := "<end of trace>", "<end of trace>"
+		if i < len(goldenLines) {
+			want = goldenLines[i]
+		}
+		if i < len(traceLines) {
+			got = traceLines[i]
+		}
+		if want != got {
+			t.Fatalf("routinetest: trace differs from %s at line %d:\n  want: %s\n  got:  %s", path, i+1, want, got)
+		}
+	}
+
+}
@@ -0,0 +1,79 @@
+package routine
+
+import "fmt"
+
+type semaphore struct {
+	capacity int
+	held     int
+}
+
+// SetSemaphore defines (or redefines) a counting semaphore with the given ID and capacity in the Routine. A semaphore
+// limits how many units of a resource can be held at once - for example, a semaphore with a capacity of 2 that each
+// Block acquires 1 unit of lets at most two Blocks through a region of their Actions at the same time (see
+// actions.NewAcquireN() and actions.NewReleaseN()). Redefining a semaphore keeps the units already held.
+func (r *Routine) SetSemaphore(id any, capacity int) {
+	if r.semaphores == nil {
+		r.semaphores = map[any]*semaphore{}
+	}
+	if sem, ok := r.semaphores[id]; ok {
+		sem.capacity = capacity
+		return
+	}
+	r.semaphores[id] = &semaphore{capacity: capacity}
+}
+
+// Semaphore returns the number of units currently held from the semaphore with the given ID, its capacity, and whether
+// it's defined.
+func (r *Routine) Semaphore(id any) (held, capacity int, ok bool) {
+	sem, ok := r.semaphores[id]
+	if !ok {
+		return 0, 0, false
+	}
+	return sem.held, sem.capacity, true
+}
+
+// TryAcquire attempts to acquire n units from the semaphore with the given ID, returning true if they were acquired, or
+// false if not enough units are available. An error wrapping ErrSemaphore is returned if n isn't positive, if the
+// semaphore isn't defined (see Routine.SetSemaphore()), or if n is larger than its capacity (so it could never be
+// acquired).
+func (r *Routine) TryAcquire(id any, n int) (bool, error) {
+
+	if n <= 0 {
+		return false, fmt.Errorf("%w: can't acquire %d units from semaphore %v", ErrSemaphore, n, id)
+	}
+
+	sem, ok := r.semaphores[id]
+	if !ok {
+		return false, fmt.Errorf("%w: semaphore %v isn't defined", ErrSemaphore, id)
+	}
+
+	if n > sem.capacity {
+		return false, fmt.Errorf("%w: can't acquire %d units from semaphore %v with a capacity of %d", ErrSemaphore, n, id, sem.capacity)
+	}
+
+	if sem.held+n > sem.capacity {
+		return false, nil
+	}
+
+	sem.held += n
+	return true, nil
+
+}
+
+// Release releases n units back to the semaphore with the given ID. Releasing more units than are held releases all of
+// them. If n isn't positive, nothing is released, and an error wrapping ErrSemaphore is reported to the Routine's error
+// handler. Note that units aren't released automatically when the Block that acquired them stops or is removed.
+func (r *Routine) Release(id any, n int) {
+	if n <= 0 {
+		r.ReportError(fmt.Errorf("%w: can't release %d units to semaphore %v", ErrSemaphore, n, id))
+		return
+	}
+	sem, ok := r.semaphores[id]
+	if !ok {
+		return
+	}
+	sem.held -= n
+	if sem.held < 0 {
+		sem.held = 0
+	}
+}
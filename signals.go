@@ -0,0 +1,65 @@
+package routine
+
+// PulseSignal emits a pulse signal with the given ID. A pulse signal is consumed by the first waiter to check it (see
+// Routine.ConsumeSignal()), and expires if it isn't consumed by the end of the Update() call following the one in which
+// it was emitted (or the next Update() call, if it was emitted between Updates), so that it only wakes up one waiter
+// that is waiting for it at the time. Pulsing a latched signal does nothing.
+func (r *Routine) PulseSignal(id any) {
+	if r.signals == nil {
+		r.signals = map[any]int{}
+	}
+	if expiry, ok := r.signals[id]; !ok || expiry != signalLatched {
+		r.signals[id] = r.ticks + 1
+	}
+}
+
+// LatchSignal sets a latched signal with the given ID. Unlike a pulse signal, a latched signal stays set (waking up
+// every waiter that checks it) until it's cleared with Routine.ClearSignal().
+func (r *Routine) LatchSignal(id any) {
+	if r.signals == nil {
+		r.signals = map[any]int{}
+	}
+	r.signals[id] = signalLatched
+}
+
+// ClearSignal clears the signal with the given ID, whether it's a pending pulse or a latched signal.
+func (r *Routine) ClearSignal(id any) {
+	delete(r.signals, id)
+}
+
+// SignalSet returns if the signal with the given ID is set (i.e. it's latched, or it's an unexpired pulse that
+// hasn't been consumed), without consuming it.
+func (r *Routine) SignalSet(id any) bool {
+
+	expiry, ok := r.signals[id]
+	if !ok {
+		return false
+	}
+
+	if expiry != signalLatched && r.ticks > expiry {
+		delete(r.signals, id)
+		return false
+	}
+
+	return true
+
+}
+
+// ConsumeSignal returns if the signal with the given ID is set, consuming it if it's a pulse signal (latched signals
+// stay set). This is what waiters (like actions.NewWaitForSignal()) use to check signals.
+func (r *Routine) ConsumeSignal(id any) bool {
+
+	if !r.SignalSet(id) {
+		return false
+	}
+
+	if r.signals[id] != signalLatched {
+		delete(r.signals, id)
+	}
+
+	return true
+
+}
+
+// signalLatched is the expiry tick used for latched signals.
+const signalLatched = -1
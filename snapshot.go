@@ -0,0 +1,247 @@
+package routine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// BlockState represents the observable state of a Block at the time a Snapshot was taken.
+type BlockState struct {
+	ID                   any
+	Running              bool
+	StopRequested        bool
+	Index                int
+	ActionCount          int
+	CurrentFrame         int
+	CurrentActionElapsed time.Duration
+	ActiveElapsed        time.Duration
+	Stopped              bool  // Whether the Block had stopped since it was last run.
+	Held                 bool  // Whether the Block was holding at its end (see EndHold).
+	Err                  error // The error the Block had failed with, if any (see Block.Err()).
+}
+
+// Snapshot represents a read-only copy of the observable state of a Routine at a specific point in time.
+type Snapshot struct {
+	Elapsed time.Duration
+	Blocks  []BlockState
+	// Properties holds shallow copies of the Blocks' local Properties (see Block.Properties()), by Block ID. Blocks
+	// without any local Properties aren't included. As Properties can hold values of any type, they aren't part of
+	// the Snapshot's hash (see Snapshot.Hash()) or of the Changes returned by Routine.DiffState().
+	Properties map[any]Properties
+}
+
+// BlockState returns the state of the Block with the given ID in the Snapshot, and a boolean indicating if it was found.
+func (s Snapshot) BlockState(id any) (BlockState, bool) {
+	for _, b := range s.Blocks {
+		if b.ID == id {
+			return b, true
+		}
+	}
+	return BlockState{}, false
+}
+
+// StateSnapshot returns a copy of the observable state of the Routine. Unlike other Routine functions, StateSnapshot can
+// be called from another goroutine while Routine.Update() is running (e.g. from a render or UI goroutine displaying the
+// Routine's status); it waits for any running Update to complete before copying the state. Because of this,
+// StateSnapshot shouldn't be called from within an Action while the Routine is updating.
+// Note that only Update() is synchronized with StateSnapshot; other functions that change the Routine (like
+// Routine.Run(), Routine.Stop(), Routine.Define(), or Block.SetIndex()) don't lock it, as they're commonly called from
+// Actions during an Update. StateSnapshot is therefore only race-free if the goroutine that owns the Routine changes it
+// exclusively from within Update() (i.e. from Actions and Update hooks); calls made between Updates must be
+// synchronized with StateSnapshot by the caller.
+func (r *Routine) StateSnapshot() Snapshot {
+	r.stateMutex.RLock()
+	defer r.stateMutex.RUnlock()
+	return r.Snapshot()
+}
+
+// Snapshot returns a copy of the observable state of the Routine. Unlike StateSnapshot(), Snapshot doesn't lock the
+// Routine, so it can be called from within Actions, but shouldn't be called from other goroutines.
+func (r *Routine) Snapshot() Snapshot {
+
+	snapshot := Snapshot{
+		Elapsed: r.elapsed,
+		Blocks:  make([]BlockState, 0, len(r.blocks)),
+	}
+
+	for _, b := range r.blocks {
+		snapshot.Blocks = append(snapshot.Blocks, BlockState{
+			ID:                   b.ID,
+			Running:              b.active,
+			StopRequested:        b.finishing,
+			Index:                b.index,
+			ActionCount:          len(b.actions),
+			CurrentFrame:         b.currentFrame,
+			CurrentActionElapsed: b.actionElapsed,
+			ActiveElapsed:        b.activeElapsed,
+			Stopped:              b.stopped,
+			Held:                 b.held,
+			Err:                  b.err,
+		})
+		if b.properties != nil && len(*b.properties) > 0 {
+			if snapshot.Properties == nil {
+				snapshot.Properties = map[any]Properties{}
+			}
+			props := Properties{}
+			props.CopyFrom(*b.properties)
+			snapshot.Properties[b.ID] = props
+		}
+	}
+
+	return snapshot
+
+}
+
+// RestoreSnapshot restores the state of the Routine's Blocks from the given Snapshot, setting each Block's index
+// (initializing the Action at that index), timers, running, stopped, held, and failed state, and local Properties (which
+// are replaced with those in the Snapshot). Blocks in the Snapshot that no longer exist in the
+// Routine are ignored, as are Blocks in the Routine that aren't in the Snapshot. This can be used to resume a Routine
+// from a previously saved point (see actions.NewSavepoint()).
+func (r *Routine) RestoreSnapshot(snapshot Snapshot) {
+
+	r.elapsed = snapshot.Elapsed
+
+	for _, state := range snapshot.Blocks {
+		if b := r.BlockByID(state.ID); b != nil {
+			b.restoreState(state)
+			b.Properties().CopyFrom(snapshot.Properties[state.ID])
+		}
+	}
+
+}
+
+func (b *Block) restoreState(state BlockState) {
+	b.index = -1
+	b.SetIndex(state.Index)
+	b.currentFrame = state.CurrentFrame
+	b.actionElapsed = state.CurrentActionElapsed
+	b.activeElapsed = state.ActiveElapsed
+	b.finishing = state.StopRequested
+	b.active = state.Running
+	b.stopped = state.Stopped
+	b.held = state.Held
+	b.err = state.Err
+}
+
+// Hash returns a 64-bit hash of the state in the Snapshot. Snapshots with equal state (including the order of their
+// Blocks) have equal hashes across processes and platforms, as long as the Blocks' IDs print the same way with
+// fmt.Sprint().
+func (s Snapshot) Hash() uint64 {
+
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+
+	writeInt := func(v int64) {
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+		h.Write(buf)
+	}
+
+	writeBool := func(v bool) {
+		if v {
+			writeInt(1)
+		} else {
+			writeInt(0)
+		}
+	}
+
+	writeInt(int64(s.Elapsed))
+	writeInt(int64(len(s.Blocks)))
+
+	for _, b := range s.Blocks {
+		id := fmt.Sprint(b.ID)
+		writeInt(int64(len(id)))
+		h.Write([]byte(id))
+		writeBool(b.Running)
+		writeBool(b.StopRequested)
+		writeInt(int64(b.Index))
+		writeInt(int64(b.ActionCount))
+		writeInt(int64(b.CurrentFrame))
+		writeInt(int64(b.CurrentActionElapsed))
+		writeInt(int64(b.ActiveElapsed))
+		writeBool(b.Stopped)
+		writeBool(b.Held)
+		writeBool(b.Err != nil)
+	}
+
+	return h.Sum64()
+
+}
+
+// StateHash returns a hash of the Routine's current state (see Snapshot.Hash()). Lockstep netcode can compare the hashes
+// of peers' Routines every frame to verify that their scripted sequences remain in sync. Note that Routines in
+// deterministic tick mode (see Routine.SetTickRate()) hash identically given identical inputs; otherwise, their timers
+// follow the wall clock and are unlikely to match.
+func (r *Routine) StateHash() uint64 {
+	return r.Snapshot().Hash()
+}
+
+// ChangeKind is simply a uint8, and represents how a Block's state changed between two points in time (see
+// Routine.DiffState()).
+type ChangeKind uint8
+
+const (
+	// BlockChanged means that the Block's state differs from its previous state.
+	BlockChanged ChangeKind = iota
+	// BlockAdded means that the Block didn't exist previously.
+	BlockAdded
+	// BlockRemoved means that the Block no longer exists.
+	BlockRemoved
+)
+
+// Change represents a change in a Block's state (see Routine.DiffState()).
+type Change struct {
+	Kind  ChangeKind
+	ID    any
+	State BlockState // The Block's current state, or its previous state if it was removed.
+}
+
+// DiffState returns the changes between the Blocks' states in the given previous Snapshot and their current states, in
+// the order of the Routine's Blocks (followed by any removed Blocks). Unchanged Blocks aren't included, so only the
+// states of Blocks that changed need to be transmitted (e.g. to resynchronize a peer whose state hash diverged; see
+// Routine.StateHash()). A changed Routine elapsed time isn't reported as a Change.
+func (r *Routine) DiffState(prev Snapshot) []Change {
+
+	current := r.Snapshot()
+	changes := []Change{}
+
+	previous := make(map[any]BlockState, len(prev.Blocks))
+	for _, state := range prev.Blocks {
+		previous[state.ID] = state
+	}
+
+	for _, state := range current.Blocks {
+		old, ok := previous[state.ID]
+		if !ok {
+			changes = append(changes, Change{Kind: BlockAdded, ID: state.ID, State: state})
+		} else if old != state {
+			changes = append(changes, Change{Kind: BlockChanged, ID: state.ID, State: state})
+		}
+		delete(previous, state.ID)
+	}
+
+	for _, state := range prev.Blocks {
+		if _, removed := previous[state.ID]; removed {
+			changes = append(changes, Change{Kind: BlockRemoved, ID: state.ID, State: state})
+		}
+	}
+
+	return changes
+
+}
+
+// ApplyChanges restores the states of the Blocks in the given Changes (see Routine.DiffState()), like
+// Routine.RestoreSnapshot() does. Changes for Blocks that don't exist in the Routine, as well as BlockRemoved Changes,
+// are ignored, as Blocks' Actions aren't part of their state. Blocks' local Properties aren't part of Changes, so they're
+// left untouched.
+func (r *Routine) ApplyChanges(changes []Change) {
+	for _, change := range changes {
+		if change.Kind == BlockRemoved {
+			continue
+		}
+		if b := r.BlockByID(change.ID); b != nil {
+			b.restoreState(change.State)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package routine
+
+import "time"
+
+// StateBuffer holds a compact copy of a Routine's mutable runtime state, for rollback netcode (see
+// Routine.CopyStateTo() and Routine.RestoreStateFrom()). A StateBuffer can be reused indefinitely; once it has grown to
+// fit the Routine's state, copying into it doesn't allocate. The zero value is ready to use.
+type StateBuffer struct {
+	elapsed    time.Duration
+	ticks      int
+	tickCount  int64
+	properties Properties
+	blocks     []blockStateBuffer
+}
+
+type blockStateBuffer struct {
+	block           *Block
+	index           int
+	needsInit       bool
+	entered         Action
+	runTime         time.Duration
+	runFrames       int
+	actionState     map[Action]any
+	currentFrame    int
+	actionElapsed   time.Duration
+	activeElapsed   time.Duration
+	active          bool
+	currentlyActive bool
+	finishing       bool
+	held            bool
+	stopped         bool
+	awaitingPre     bool
+	preElapsed      time.Duration
+	err             error
+	result          any
+	hasProperties   bool
+	properties      Properties
+}
+
+// CopyStateTo copies the Routine's mutable runtime state into the given StateBuffer, replacing its contents. Only state
+// that changes as the Routine runs is stored - its time, the Routine's and Blocks' Properties, and each Block's
+// index, frame and time counters, running state, and the state its Actions store in it (see Block.SetActionState()) -
+// not the Blocks' Actions themselves. The Routine's signals, semaphores, queues, cooldowns and inbox, and the state of
+// its and its Blocks' random sources (see Routine.Rand()), aren't stored either.
+// This is designed to be called every simulation frame (e.g. to keep a ring of StateBuffers for rollback), and doesn't
+// allocate once the buffer has grown to fit the Routine's state. Property and Action state values are copied shallowly.
+func (r *Routine) CopyStateTo(buffer *StateBuffer) {
+
+	buffer.elapsed = r.elapsed
+	buffer.ticks = r.ticks
+	buffer.tickCount = r.tickCount
+	buffer.properties.CopyFrom(*r.properties)
+
+	if cap(buffer.blocks) < len(r.blocks) {
+		buffer.blocks = append(buffer.blocks[:cap(buffer.blocks)], make([]blockStateBuffer, len(r.blocks)-cap(buffer.blocks))...)
+	}
+	buffer.blocks = buffer.blocks[:len(r.blocks)]
+
+	for i, b := range r.blocks {
+		state := &buffer.blocks[i]
+		state.block = b
+		state.index = b.index
+		state.needsInit = b.needsInit
+		state.entered = b.entered
+		state.runTime = b.runTime
+		state.runFrames = b.runFrames
+		if state.actionState == nil && len(b.actionState) > 0 {
+			state.actionState = make(map[Action]any, len(b.actionState))
+		}
+		for action := range state.actionState {
+			delete(state.actionState, action)
+		}
+		for action, value := range b.actionState {
+			state.actionState[action] = value
+		}
+		state.currentFrame = b.currentFrame
+		state.actionElapsed = b.actionElapsed
+		state.activeElapsed = b.activeElapsed
+		state.active = b.active
+		state.currentlyActive = b.currentlyActive
+		state.finishing = b.finishing
+		state.held = b.held
+		state.stopped = b.stopped
+		state.awaitingPre = b.awaitingPre
+		state.preElapsed = b.preElapsed
+		state.err = b.err
+		state.result = b.result
+		state.hasProperties = b.properties != nil
+		if state.hasProperties {
+			state.properties.CopyFrom(*b.properties)
+		}
+	}
+
+}
+
+// RestoreStateFrom restores the Routine's mutable runtime state from the given StateBuffer (see Routine.CopyStateTo()).
+// Blocks that have been removed from the Routine since the state was copied are ignored, as are Blocks that have been
+// added since. Each restored Block is put back on the Action it was on without initializing that Action again, along
+// with the state its Actions stored in it; as a result, Actions that keep their progress in their Block (like
+// actions.Wait and the other timed actions in the actions package) resume exactly where they were. Actions that keep
+// their progress on themselves instead (like actions.NestedCollection, actions.Gate or actions.Timing) aren't rolled
+// back, and carry on from where they are when the state is restored. Nothing outside of the buffer (see
+// Routine.CopyStateTo()) is rolled back either.
+func (r *Routine) RestoreStateFrom(buffer *StateBuffer) {
+
+	r.elapsed = buffer.elapsed
+	r.ticks = buffer.ticks
+	r.tickCount = buffer.tickCount
+	r.properties.CopyFrom(buffer.properties)
+
+	// Blocks are only ever appended to or removed from the Routine, so the buffered Blocks that still exist are found
+	// in the same order.
+	next := 0
+
+	for i := range buffer.blocks {
+
+		state := &buffer.blocks[i]
+
+		found := -1
+		for j := next; j < len(r.blocks); j++ {
+			if r.blocks[j] == state.block {
+				found = j
+				break
+			}
+		}
+
+		if found < 0 {
+			continue
+		}
+
+		next = found + 1
+		b := state.block
+
+		b.index = state.index
+		b.needsInit = state.needsInit
+		b.entered = state.entered
+		b.runTime = state.runTime
+		b.runFrames = state.runFrames
+		for action := range b.actionState {
+			if _, ok := state.actionState[action]; !ok {
+				delete(b.actionState, action)
+			}
+		}
+		for action, value := range state.actionState {
+			b.SetActionState(action, value)
+		}
+		b.currentFrame = state.currentFrame
+		b.actionElapsed = state.actionElapsed
+		b.activeElapsed = state.activeElapsed
+		b.active = state.active
+		b.currentlyActive = state.currentlyActive
+		b.finishing = state.finishing
+		b.held = state.held
+		b.stopped = state.stopped
+		b.awaitingPre = state.awaitingPre
+		b.preElapsed = state.preElapsed
+		b.err = state.err
+		b.result = state.result
+
+		if state.hasProperties {
+			b.Properties().CopyFrom(state.properties)
+		} else if b.properties != nil {
+			b.properties.Clear()
+		}
+
+	}
+
+}
@@ -0,0 +1,43 @@
+package routine
+
+import "time"
+
+// SetTickRate puts the Routine into deterministic tick mode with the given number of ticks per second, or takes it out
+// of tick mode if the rate is 0 or less. In tick mode, the wall clock is never read; instead, each Update() call
+// advances the Routine's time by exactly one tick (1/rate seconds), using integer arithmetic only, and the time scale
+// (see Routine.SetTimeScale()) is ignored. Actions that would otherwise wait on the wall clock (like actions.Wait)
+// convert their durations to ticks (see Routine.DurationToTicks()) and count Updates instead. Together with seeding the
+// Routine's random source (see Routine.SetSeed()), this guarantees bit-identical behavior across platforms, as
+// required by lockstep multiplayer games.
+func (r *Routine) SetTickRate(ticksPerSecond int) {
+	if ticksPerSecond < 0 {
+		ticksPerSecond = 0
+	}
+	if ticksPerSecond != r.tickRate {
+		r.tickRate = ticksPerSecond
+		r.tickCount = 0
+		r.lastUpdate = time.Time{}
+	}
+}
+
+// TickRate returns the number of ticks per second the Routine runs at in deterministic tick mode, or 0 if it isn't in
+// tick mode (see Routine.SetTickRate()).
+func (r *Routine) TickRate() int {
+	return r.tickRate
+}
+
+// DurationToTicks converts the given duration to a number of ticks at the Routine's tick rate (see
+// Routine.SetTickRate()), rounding up so that waiting for that many ticks never waits less than the duration. If the
+// Routine isn't in tick mode, DurationToTicks returns 0.
+func (r *Routine) DurationToTicks(duration time.Duration) int {
+	if r.tickRate <= 0 || duration <= 0 {
+		return 0
+	}
+	rate := int64(r.tickRate)
+	return int((int64(duration)*rate + int64(time.Second) - 1) / int64(time.Second))
+}
+
+// tickTime returns the time at which the given tick begins in tick mode, measured from the first tick.
+func (r *Routine) tickTime(tick int64) time.Duration {
+	return time.Duration(tick * int64(time.Second) / int64(r.tickRate))
+}
@@ -0,0 +1,327 @@
+// twee is a package that imports stories written in Twee (the text format Twine exports to; see
+// https://github.com/iftechfoundation/twine-specs) into a Routine, so that writers can author branching stories with
+// Twine and run them directly. Each passage becomes a Block, headed by a Label with the passage's name; its text is
+// shown line by line through a dialogue.Presenter, and its links are then offered as choices (see dialogue.NewChoice()),
+// each of which runs the linked passage's Block. A passage without links ends the story.
+//
+// Only plain text and links ([[Target]], [[Text|Target]], [[Text->Target]] and [[Target<-Text]]) are supported; story
+// format macros are shown as-is. Passages tagged "script" or "stylesheet", and the special StoryTitle and StoryData
+// passages, aren't imported.
+package twee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/actions"
+	"github.com/solarlune/routine/dialogue"
+)
+
+// ErrSyntax is returned when a story can't be parsed, or links to a passage that doesn't exist.
+var ErrSyntax = errors.New("twee: syntax error")
+
+type passageID struct{ passage string }
+
+// BlockID returns the ID of the Block that runs the passage with the given name.
+func BlockID(passage string) any { return passageID{passage} }
+
+// Story represents a parsed Twee story.
+type Story struct {
+	Title    string     // The story's title, from its StoryTitle passage.
+	Start    string     // The name of the passage the story starts at.
+	Passages []*Passage // The story's passages, in the order they were written.
+}
+
+// Passage represents a passage in a Story.
+type Passage struct {
+	Name  string
+	Tags  []string
+	Text  string
+	Links []Link
+	line  int
+}
+
+// Link represents a link from a Passage to another.
+type Link struct {
+	Text   string
+	Target string
+}
+
+// Parse parses the given Twee source into a Story, returning an error wrapping ErrSyntax if it can't be parsed, if a
+// passage links to a passage that doesn't exist, or if the story's starting passage doesn't exist. The story starts at
+// the passage named by its StoryData passage, or at the passage named "Start" if there is none.
+func Parse(source string) (*Story, error) {
+
+	story := &Story{Start: "Start"}
+	names := map[string]int{}
+
+	var current *Passage
+	var body []string
+
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		text := strings.TrimSpace(strings.Join(body, "\n"))
+		body = nil
+		switch {
+		case current.Name == "StoryTitle":
+			story.Title = text
+		case current.Name == "StoryData":
+			data := struct {
+				Start string `json:"start"`
+			}{}
+			if err := json.Unmarshal([]byte(text), &data); err != nil {
+				return fmt.Errorf("%w: line %d: invalid StoryData: %v", ErrSyntax, current.line, err)
+			}
+			if data.Start != "" {
+				story.Start = data.Start
+			}
+		case hasTag(current, "script"), hasTag(current, "stylesheet"):
+		default:
+			current.Text = text
+			current.Links = parseLinks(text)
+			story.Passages = append(story.Passages, current)
+		}
+		return nil
+	}
+
+	for i, line := range strings.Split(source, "\n") {
+
+		lineNumber := i + 1
+
+		if !strings.HasPrefix(line, "::") {
+			if current != nil {
+				body = append(body, strings.TrimRight(line, "\r"))
+			} else if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("%w: line %d: text before the first passage", ErrSyntax, lineNumber)
+			}
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return nil, err
+		}
+
+		passage, err := parseHeader(strings.TrimSpace(line[2:]), lineNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		if previous, exists := names[passage.Name]; exists {
+			return nil, fmt.Errorf("%w: line %d: passage %q is already defined on line %d", ErrSyntax, lineNumber, passage.Name, previous)
+		}
+		names[passage.Name] = lineNumber
+
+		current = passage
+
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if story.Passage(story.Start) == nil {
+		return nil, fmt.Errorf("%w: the starting passage %q doesn't exist", ErrSyntax, story.Start)
+	}
+
+	for _, passage := range story.Passages {
+		for _, link := range passage.Links {
+			if story.Passage(link.Target) == nil {
+				return nil, fmt.Errorf("%w: line %d: passage %q links to unknown passage %q", ErrSyntax, passage.line, passage.Name, link.Target)
+			}
+		}
+	}
+
+	return story, nil
+
+}
+
+// Passage returns the Passage with the given name, or nil if there is none.
+func (s *Story) Passage(name string) *Passage {
+	for _, passage := range s.Passages {
+		if passage.Name == name {
+			return passage
+		}
+	}
+	return nil
+}
+
+// Define defines a Block for each of the Story's passages in the Routine (see BlockID()), which show the passage through
+// the given Presenter, and returns the Block of the starting passage; running it starts the story. If the Routine
+// already has Blocks for some of the passages and its DefinePolicy isn't routine.ReplaceExisting, an error is returned
+// and the Routine is left untouched.
+func (s *Story) Define(r *routine.Routine, p *dialogue.Presenter) (*routine.Block, error) {
+
+	for _, passage := range s.Passages {
+		if r.DefinePolicy() != routine.ReplaceExisting && r.BlockByID(BlockID(passage.Name)) != nil {
+			return nil, fmt.Errorf("%w: passage %q is already defined in the routine", routine.ErrDuplicateID, passage.Name)
+		}
+	}
+
+	for _, passage := range s.Passages {
+		if _, err := r.DefineE(BlockID(passage.Name), passage.actions(p)...); err != nil {
+			return nil, fmt.Errorf("passage %q: %w", passage.Name, err)
+		}
+	}
+
+	return r.BlockByID(BlockID(s.Start)), nil
+
+}
+
+// Define parses the given Twee source (see Parse()) and defines its passages in the Routine (see Story.Define()),
+// returning the Block of the starting passage.
+func Define(r *routine.Routine, source string, p *dialogue.Presenter) (*routine.Block, error) {
+	story, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return story.Define(r, p)
+}
+
+// actions returns the Actions of the Block that runs the Passage.
+func (passage *Passage) actions(p *dialogue.Presenter) []routine.Action {
+
+	compiled := []routine.Action{actions.NewLabel(passage.Name)}
+
+	for _, line := range strings.Split(passage.Text, "\n") {
+		// Lines that consist only of links are offered as choices instead.
+		if text := strings.TrimSpace(replaceLinks(line)); text != "" && strings.TrimSpace(removeLinks(line)) != "" {
+			compiled = append(compiled, dialogue.NewLine(p, "", text))
+		}
+	}
+
+	if len(passage.Links) > 0 {
+		options := make([]dialogue.Option, 0, len(passage.Links))
+		for _, link := range passage.Links {
+			options = append(options, dialogue.Option{Text: link.Text, Actions: []routine.Action{newGoTo(passage.Name, link.Target)}})
+		}
+		compiled = append(compiled, dialogue.NewChoice(p, options...))
+	}
+
+	return compiled
+
+}
+
+// newGoTo returns an Action that moves from the passage with the given name to the target passage.
+func newGoTo(from, target string) *actions.Function {
+	return actions.NewFunction(func(block *routine.Block) routine.Flow {
+		if target == from {
+			block.JumpTo(target)
+			return routine.FlowNext
+		}
+		r := block.Routine()
+		r.Restart(BlockID(target))
+		r.Run(BlockID(target))
+		return routine.FlowFinish
+	}).SetName("GoTo " + target)
+}
+
+func hasTag(passage *Passage, tag string) bool {
+	for _, t := range passage.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHeader parses a passage header ("Name [tags] {metadata}", without the leading "::").
+func parseHeader(header string, lineNumber int) (*Passage, error) {
+
+	passage := &Passage{line: lineNumber}
+
+	name := strings.Builder{}
+	rest := ""
+
+	for i := 0; i < len(header); i++ {
+		c := header[i]
+		if c == '\\' && i+1 < len(header) {
+			name.WriteByte(header[i+1])
+			i++
+			continue
+		}
+		if c == '[' || c == '{' {
+			rest = header[i:]
+			break
+		}
+		name.WriteByte(c)
+	}
+
+	passage.Name = strings.TrimSpace(name.String())
+	if passage.Name == "" {
+		return nil, fmt.Errorf("%w: line %d: passage has no name", ErrSyntax, lineNumber)
+	}
+
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("%w: line %d: unterminated tags", ErrSyntax, lineNumber)
+		}
+		passage.Tags = strings.Fields(rest[1:end])
+	}
+
+	return passage, nil
+
+}
+
+// parseLinks returns the links in the given passage text, in the order they appear.
+func parseLinks(text string) []Link {
+	links := []Link{}
+	mapLinks(text, func(link Link) string {
+		links = append(links, link)
+		return ""
+	})
+	return links
+}
+
+func parseLink(link string) Link {
+	if i := strings.Index(link, "|"); i >= 0 {
+		return Link{Text: link[:i], Target: link[i+1:]}
+	}
+	if i := strings.Index(link, "->"); i >= 0 {
+		return Link{Text: link[:i], Target: link[i+2:]}
+	}
+	if i := strings.Index(link, "<-"); i >= 0 {
+		return Link{Text: link[i+2:], Target: link[:i]}
+	}
+	return Link{Text: link, Target: link}
+}
+
+// replaceLinks returns the given text with each link replaced by its text.
+func replaceLinks(text string) string {
+	return mapLinks(text, func(link Link) string { return link.Text })
+}
+
+// removeLinks returns the given text with each link removed.
+func removeLinks(text string) string {
+	return mapLinks(text, func(link Link) string { return "" })
+}
+
+// mapLinks returns the given text with each link replaced by the result of calling replace with it.
+func mapLinks(text string, replace func(link Link) string) string {
+
+	builder := strings.Builder{}
+
+	for {
+		start := strings.Index(text, "[[")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start:], "]]")
+		if end < 0 {
+			break
+		}
+		builder.WriteString(text[:start])
+		builder.WriteString(replace(parseLink(text[start+2 : start+end])))
+		text = text[start+end+2:]
+	}
+
+	builder.WriteString(text)
+
+	return builder.String()
+
+}
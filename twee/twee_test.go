@@ -0,0 +1,91 @@
+package twee_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/solarlune/routine"
+	"github.com/solarlune/routine/dialogue"
+	"github.com/solarlune/routine/twee"
+)
+
+const story = `:: StoryTitle
+The Cave
+
+:: StoryData
+{"start": "Entrance", "ifid": "00000000-0000-0000-0000-000000000000"}
+
+:: Entrance [dark] {"position":"100,100"}
+You stand at the mouth of a cave.
+Go [[inside->Tunnel]] or [[Leave|Outside]]?
+
+:: Tunnel
+It's too dark to see.
+[[Entrance<-Turn back]]
+
+:: Outside
+You leave.
+`
+
+func TestStory(t *testing.T) {
+
+	s, err := twee.Parse(story)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Title != "The Cave" || s.Start != "Entrance" || len(s.Passages) != 3 {
+		t.Fatalf("parsed title %q, start %q and %d passages", s.Title, s.Start, len(s.Passages))
+	}
+
+	lines := []string{}
+	offered := [][]string{}
+	picks := []int{0, 0, 1}
+
+	r := routine.New()
+	start, err := s.Define(r, &dialogue.Presenter{
+		ShowLine:    func(speaker, text string) { lines = append(lines, text) },
+		ShowChoices: func(choices []string) { offered = append(offered, choices) },
+		Chosen: func() int {
+			pick := picks[0]
+			picks = picks[1:]
+			return pick
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start.Run()
+
+	for i := 0; i < 30 && r.Running(); i++ {
+		r.Update()
+	}
+
+	if r.Running() {
+		t.Fatalf("story didn't end")
+	}
+
+	expected := []string{
+		"You stand at the mouth of a cave.", "Go inside or Leave?",
+		"It's too dark to see.",
+		"You stand at the mouth of a cave.", "Go inside or Leave?",
+		"You leave.",
+	}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Fatalf("showed %q, expected %q", lines, expected)
+	}
+
+	if len(offered) != 3 || strings.Join(offered[1], "|") != "Turn back" {
+		t.Fatalf("offered %q", offered)
+	}
+
+}
+
+func TestUnknownLink(t *testing.T) {
+	_, err := twee.Parse(":: Start\n[[Nowhere]]\n")
+	if !errors.Is(err, twee.ErrSyntax) || !strings.Contains(err.Error(), "Nowhere") {
+		t.Fatalf("expected a syntax error for the unknown passage, got %v", err)
+	}
+}